@@ -0,0 +1,184 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/brumhard/kindacool/pkg/kindacool"
+
+	"github.com/spf13/cobra"
+)
+
+var ErrNoSpecFiles = errors.New("at least one spec file is required (-f)")
+
+func BuildApplyCommand() *cobra.Command {
+	var (
+		manager       = &kindacool.Manager{}
+		files         []string
+		dryRun        bool
+		skipPreflight bool
+		retries       int
+		waitTimeout   time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Create or update clusters from spec files",
+		Long: fmt.Sprintf(`The apply command creates or updates all clusters that are defined in the given spec files.
+
+A spec file contains one or more cluster specs in YAML or JSON format like:
+
+	apiVersion: %[2]s
+	kind: %[3]s
+	metadata:
+	  name: my-cluster
+	  tags:
+	    team: infra
+	spec:
+	  flavor: m4.large
+	  nodeCount: 3
+	  privateNetworkName: my-network
+
+The spec supports the same settings as the flags of '%[1]s cluster create'.
+Multiple specs can be separated with '---' or defined as a list.
+All specs are validated before any cluster is changed.`, CLI, kindacool.SpecAPIVersion, kindacool.SpecKind),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return setupManager(cmd, manager)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			specs, err := readSpecs(cmd, files)
+			if err != nil {
+				return err
+			}
+
+			for i := range specs {
+				spec := &specs[i]
+
+				clusterManager := manager
+				if len(specs) > 1 {
+					clusterManager = manager.ForCluster(spec.Metadata.Name)
+				} else {
+					manager.Options.Name = spec.Metadata.Name
+				}
+
+				if err := clusterManager.Options.ValidateName(); err != nil {
+					return err
+				}
+
+				if dryRun {
+					plan, err := clusterManager.Preview(cmd.Context(), &spec.Spec)
+					if err != nil {
+						return err
+					}
+
+					if err := printPlan(cmd.OutOrStdout(), plan); err != nil {
+						return err
+					}
+
+					continue
+				}
+
+				runOpts := kindacool.RunOptions{
+					Tags: spec.Metadata.Tags, SkipPreflight: skipPreflight, Retries: retries,
+					WaitTimeout: waitTimeoutFor(cmd, waitTimeout, &spec.Spec),
+				}
+				runErr := clusterManager.Run(cmd.Context(), &spec.Spec, runOpts)
+				if runErr != nil && !errors.Is(runErr, kindacool.ErrNotReady) {
+					recordCluster(cmd, ClusterResult{Name: spec.Metadata.Name, Status: kindacool.StatusFailed, Error: runErr.Error()})
+					return fmt.Errorf("failed to apply cluster %q: %w", spec.Metadata.Name, runErr)
+				}
+
+				// the kubeconfig is still written if the cluster isn't ready, to be able to investigate
+				if err := writeKubeconfig(cmd, clusterManager, runErr); err != nil {
+					return err
+				}
+
+				if runErr != nil {
+					return fmt.Errorf("failed to apply cluster %q: %w", spec.Metadata.Name, runErr)
+				}
+
+				logWaitHint(clusterManager, runOpts.WaitTimeout, &spec.Spec)
+			}
+
+			return nil
+		},
+	}
+
+	addSpecFileFlag(cmd, &files)
+	cmd.Flags().BoolVarP(&manager.Options.Verbose, "verbose", "v", false, "Enable verbose pulumi output.")
+	addCloudFlags(cmd.Flags(), &manager.Options.Cloud)
+	addPulumiFlags(cmd.Flags(), &manager.Options.Pulumi)
+	addPluginFlags(cmd.Flags(), &manager.Options.Plugins)
+	_ = cmd.RegisterFlagCompletionFunc("cloud", completeCloudNames)
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, dryRunFlagUsage)
+	cmd.Flags().BoolVar(&skipPreflight, "skip-preflight", false, skipPreflightFlagUsage)
+	addRetriesFlag(cmd, &retries)
+	addWaitTimeoutFlag(cmd, &waitTimeout)
+
+	return cmd
+}
+
+func addSpecFileFlag(cmd *cobra.Command, files *[]string) {
+	cmd.Flags().StringSliceVarP(
+		files,
+		"filename", "f", nil,
+		`Spec file that contains the clusters. Use "-" to read from stdin.
+The flag can be defined multiple times like -f a.yaml -f b.yaml`,
+	)
+}
+
+// readSpecs parses and validates all specs from the given files.
+func readSpecs(cmd *cobra.Command, files []string) ([]kindacool.ClusterSpec, error) {
+	if len(files) == 0 {
+		return nil, ErrNoSpecFiles
+	}
+
+	var (
+		specs []kindacool.ClusterSpec
+		errs  []error
+		names = map[string]string{}
+	)
+	for _, file := range files {
+		var reader io.Reader = cmd.InOrStdin()
+		if file != "-" {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+
+			reader = bytes.NewReader(content)
+		}
+
+		fileSpecs, err := kindacool.ParseSpecs(reader, file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, spec := range fileSpecs {
+			if other, ok := names[spec.Metadata.Name]; ok {
+				errs = append(errs, fmt.Errorf(
+					"%s: %w", file,
+					&kindacool.FieldError{
+						Field:   "metadata.name",
+						Problem: fmt.Sprintf("cluster %q is already defined in %s", spec.Metadata.Name, other),
+					},
+				))
+				continue
+			}
+
+			names[spec.Metadata.Name] = file
+			specs = append(specs, spec)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return specs, nil
+}
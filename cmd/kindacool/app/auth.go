@@ -0,0 +1,133 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/brumhard/kindacool/pkg/kindacool"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// outputEnv prints shell exports to authenticate with the created credential.
+	outputEnv = "env"
+	// defaultAppCredLifetime keeps forgotten credentials from working forever.
+	defaultAppCredLifetime = 90 * 24 * time.Hour
+)
+
+func BuildAuthCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Manage the credentials for OpenStack",
+		Long: fmt.Sprintf(`The auth command helps to set up the credentials %[1]s uses for OpenStack.
+
+Instead of a user's password, %[1]s can authenticate with an application credential.
+Set it with --application-credential-id and $KINDACOOL_APPLICATION_CREDENTIAL_SECRET,
+with $OS_APPLICATION_CREDENTIAL_ID and $OS_APPLICATION_CREDENTIAL_SECRET or in a clouds.yaml entry.`, CLI),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			_, err := applyUserConfig(cmd)
+			return err
+		},
+	}
+
+	cmd.AddCommand(BuildCreateAppCredCommand())
+
+	return cmd
+}
+
+func BuildCreateAppCredCommand() *cobra.Command {
+	var (
+		cloudOptions kindacool.CloudOptions
+		opts         kindacool.ApplicationCredentialOptions
+		expiresIn    time.Duration
+		output       string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "create-appcred",
+		Short: "Create an application credential for the current project",
+		Long: fmt.Sprintf(`The create-appcred command creates an application credential with the current credentials.
+
+The credential is scoped to the project of the current credentials and expires after --expires-in.
+With --role it is limited to the given roles, otherwise it has all the roles of the current user.
+The secret is only shown once, store it right away, e.g. as a secret of the CI system.
+
+To use the credential in the current shell:
+	$ eval "$(%[1]s auth create-appcred -o env)"`, CLI),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.Name == "" {
+				opts.Name = fmt.Sprintf("%s-%s", CLI, time.Now().Format("20060102-150405"))
+			}
+
+			if expiresIn > 0 {
+				opts.ExpiresAt = time.Now().Add(expiresIn)
+			}
+
+			cloud, err := kindacool.NewCloud(cmd.Context(), cloudOptions)
+			if err != nil {
+				return err
+			}
+
+			credential, err := cloud.CreateApplicationCredential(opts)
+			if err != nil {
+				return err
+			}
+
+			if output == outputEnv {
+				printAppCredEnv(cmd.OutOrStdout(), credential)
+				return nil
+			}
+
+			return printOutput(cmd.OutOrStdout(), output, credential, func(w io.Writer) {
+				fmt.Fprintf(w, "ID:\t%s\n", credential.ID)
+				fmt.Fprintf(w, "Name:\t%s\n", credential.Name)
+				fmt.Fprintf(w, "Secret:\t%s\n", credential.Secret)
+				fmt.Fprintf(w, "Project ID:\t%s\n", credential.ProjectID)
+				fmt.Fprintf(w, "Roles:\t%s\n", strings.Join(credential.Roles, ", "))
+				expiresAt := "never"
+				if credential.ExpiresAt != nil {
+					expiresAt = credential.ExpiresAt.Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "Expires:\t%s\n", expiresAt)
+			})
+		},
+	}
+
+	addCloudFlags(cmd.Flags(), &cloudOptions)
+	_ = cmd.RegisterFlagCompletionFunc("cloud", completeCloudNames)
+	cmd.Flags().StringVar(&opts.Name, "credential-name", "", fmt.Sprintf("Name of the credential. Defaults to %s-<timestamp>.", CLI))
+	cmd.Flags().StringVar(&opts.Description, "description", fmt.Sprintf("Created by %s", CLI), "Description of the credential.")
+	cmd.Flags().DurationVar(
+		&expiresIn, "expires-in", defaultAppCredLifetime,
+		"Time after which the credential expires. Use 0 for a credential that never expires.",
+	)
+	cmd.Flags().StringSliceVar(&opts.Roles, "role", nil, "Role the credential is limited to. Can be repeated.")
+	cmd.Flags().BoolVar(
+		&opts.Unrestricted, "unrestricted", false,
+		"Allow the credential to create and delete other application credentials and trusts.",
+	)
+	addOutputFlag(cmd, &output, outputTable, outputEnv, outputJSON, outputYAML)
+
+	return cmd
+}
+
+// printAppCredEnv writes the shell exports to authenticate with the credential.
+func printAppCredEnv(w io.Writer, credential *kindacool.ApplicationCredential) {
+	env := [][2]string{
+		{"OS_AUTH_TYPE", "v3applicationcredential"},
+		{"OS_AUTH_URL", credential.AuthURL},
+		{"OS_APPLICATION_CREDENTIAL_ID", credential.ID},
+		{"OS_APPLICATION_CREDENTIAL_SECRET", credential.Secret},
+	}
+	if credential.Region != "" {
+		env = append(env, [2]string{"OS_REGION_NAME", credential.Region})
+	}
+
+	for _, variable := range env {
+		fmt.Fprintf(w, "export %s=%q\n", variable[0], variable[1])
+	}
+}
@@ -7,12 +7,17 @@ import (
 	"math/rand"
 	"time"
 
+	"github.com/brumhard/kindacool/pkg/k3s"
 	"github.com/brumhard/kindacool/pkg/kindacool"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
-const outputPrefixChangeInterval = 100 * time.Millisecond
+const (
+	outputPrefixChangeInterval = 100 * time.Millisecond
+	waitTimeoutFlag            = "waitTimeout"
+)
 
 func BuildClusterCommand() *cobra.Command {
 	manager := &kindacool.Manager{}
@@ -25,38 +30,187 @@ func BuildClusterCommand() *cobra.Command {
 By default the name "kindacool" is used for all cluster operations.
 If you want to manage a second cluster set the --name flag.`,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			// Enable swapping out stdout/stderr for testing
-			manager.Logger = log.New(cmd.OutOrStderr(), "🚀 ", 0)
-			// use random emoji as prefix
-			go func(ctx context.Context) {
-				emojis := []string{"🚀", "💃", "✨", "🔥", "🦥", "👽", "👾", "👀", "💅"}
-				for {
-					select {
-					case <-ctx.Done():
-						return
-					case <-time.After(outputPrefixChangeInterval):
-					}
-					//nolint:gosec // not relevant for security
-					manager.Logger.SetPrefix(emojis[rand.Intn(len(emojis)-1)] + " ")
-				}
-			}(cmd.Context())
-
-			if err := kindacool.EnsureEnvironment(cmd.Context()); err != nil {
-				return err
-			}
-
-			return manager.Options.Validate()
+			return setupManager(cmd, manager)
 		},
 	}
 
-	cmd.PersistentFlags().StringVarP(&manager.Options.Name, "name", "n", "kindacool", "Name of the cluster to manage.")
+	cmd.PersistentFlags().StringVarP(
+		&manager.Options.Name, "name", "n", "kindacool",
+		"Name of the cluster to manage. Names of new clusters may contain lower case letters, digits and '-' with at most 50 characters.",
+	)
+	_ = cmd.RegisterFlagCompletionFunc("name", completeClusterNames)
 	cmd.PersistentFlags().BoolVarP(&manager.Options.Verbose, "verbose", "v", false, "Enable verbose pulumi output.")
+	addCloudFlags(cmd.PersistentFlags(), &manager.Options.Cloud)
+	addPulumiFlags(cmd.PersistentFlags(), &manager.Options.Pulumi)
+	addPluginFlags(cmd.PersistentFlags(), &manager.Options.Plugins)
+	_ = cmd.RegisterFlagCompletionFunc("cloud", completeCloudNames)
 
 	cmd.AddCommand(BuildCreateCommand(manager))
 	cmd.AddCommand(BuildDestroyCommand(manager))
 	cmd.AddCommand(BuildLsCommand(manager))
 	cmd.AddCommand(BuildKubeconfigCommand(manager))
 	cmd.AddCommand(BuildSSHKeyCommand(manager))
+	cmd.AddCommand(BuildDescribeCommand(manager))
+	cmd.AddCommand(BuildCancelCommand(manager))
+	cmd.AddCommand(BuildUnlockCommand(manager))
+	cmd.AddCommand(BuildSSHCommand(manager))
+	cmd.AddCommand(BuildExecCommand(manager))
+	cmd.AddCommand(BuildCopyCommand(manager))
+	cmd.AddCommand(BuildTunnelCommand(manager))
+	cmd.AddCommand(BuildWaitCommand(manager))
 
 	return cmd
 }
+
+// setupManager prepares the manager for the given command and checks that
+// the environment is ready to be used.
+func setupManager(cmd *cobra.Command, manager *kindacool.Manager) error {
+	if _, err := applyUserConfig(cmd); err != nil {
+		return err
+	}
+
+	// the options are checked before any pulumi call
+	if err := manager.Options.Validate(); err != nil {
+		return err
+	}
+
+	// commands for spec files have no --name flag, the names of their clusters are checked per cluster
+	if cmd.Flags().Lookup("name") != nil {
+		if err := manager.Options.ValidateName(); err != nil {
+			return err
+		}
+	}
+
+	logFormat, err := kindacool.ParseLogFormat(cmd.Flag(logFormatFlag).Value.String())
+	if err != nil {
+		return err
+	}
+
+	// Enable swapping out stdout/stderr for testing
+	manager.Logger = kindacool.NewLogger(cmd.OutOrStderr(), logFormat, "🚀 ")
+	if logFormat == kindacool.LogFormatText {
+		go rotatePrefix(cmd.Context(), manager.Logger)
+	}
+
+	manager.LogStage(kindacool.StageEnvironment, "Checking environment")
+	if err := manager.EnsureEnvironment(cmd.Context()); err != nil {
+		return err
+	}
+
+	if version, err := kindacool.PulumiVersion(); err == nil {
+		if err := kindacool.CheckPulumiVersion(version); err != nil {
+			manager.Logger.Printf("Warning: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+// addCloudFlags adds the flags to select the OpenStack cloud and credentials.
+func addCloudFlags(flags *pflag.FlagSet, options *kindacool.CloudOptions) {
+	flags.StringVar(
+		&options.Cloud, "cloud", "",
+		"Name of the cloud in clouds.yaml to create the cluster in. Defaults to $OS_CLOUD, without a cloud the .openrc env vars are used. "+
+			"Existing clusters always use the cloud they were created in.",
+	)
+	flags.StringVar(
+		&options.Region, "region", "",
+		"OpenStack region to create the cluster in. Defaults to the region of the cloud or $OS_REGION_NAME.",
+	)
+	flags.StringVar(
+		&options.ApplicationCredentialID, "application-credential-id", "",
+		"ID of an application credential to authenticate with instead of the credentials of the cloud or .openrc file.",
+	)
+	flags.StringVar(
+		&options.ApplicationCredentialSecret, "application-credential-secret", "",
+		"Secret of the application credential. Prefer $KINDACOOL_APPLICATION_CREDENTIAL_SECRET or $OS_APPLICATION_CREDENTIAL_SECRET "+
+			"to keep it out of the shell history.",
+	)
+}
+
+// addPulumiFlags adds the flags to install the managed pulumi CLI.
+func addPulumiFlags(flags *pflag.FlagSet, options *kindacool.PulumiOptions) {
+	flags.StringVar(
+		&options.Mirror, "pulumi-mirror", kindacool.DefaultPulumiMirror,
+		fmt.Sprintf("Base URL to download pulumi v%s and its checksums from if there's no pulumi CLI in $PATH.", kindacool.ManagedPulumiVersion),
+	)
+	flags.StringVar(
+		&options.Archive, "pulumi-archive", "",
+		"Local pulumi release archive to install instead of downloading it from the mirror.",
+	)
+	flags.StringVar(
+		&options.Checksum, "pulumi-checksum", "",
+		"SHA-256 checksum of the pulumi archive. Defaults to the one in the release's checksums file. "+
+			"Required for archives from other mirrors.",
+	)
+}
+
+// addPluginFlags adds the flags to select the versions and the source of the pulumi plugins.
+func addPluginFlags(flags *pflag.FlagSet, options *kindacool.PluginOptions) {
+	flags.StringToStringVar(
+		&options.Versions, "plugin-version", nil,
+		"Version of a pulumi plugin as name=version, e.g. openstack=v3.15.0. "+
+			"Defaults to the versions of the plugins' Go SDKs kindacool is built with.",
+	)
+	flags.StringVar(
+		&options.Source, "plugin-source", "",
+		"Directory with plugin archives like pulumi-resource-<name>-<version>-<os>-<arch>.tar.gz or URL of a plugin server "+
+			"to install the pulumi plugins from. Defaults to pulumi's servers.",
+	)
+}
+
+// addRetriesFlag adds the flag to retry the deployment after transient errors.
+func addRetriesFlag(cmd *cobra.Command, retries *int) {
+	cmd.Flags().IntVar(
+		retries, "retries", kindacool.DefaultRetries,
+		`Number of times to retry creating or updating the resources if it failed only because of transient errors,
+e.g. an unavailable OpenStack API or a node that can't be reached via SSH yet.`,
+	)
+}
+
+// addWaitTimeoutFlag adds the flag to wait for the cluster to become ready.
+func addWaitTimeoutFlag(cmd *cobra.Command, timeout *time.Duration) {
+	cmd.Flags().DurationVar(
+		timeout, waitTimeoutFlag, kindacool.DefaultWaitTimeout,
+		`Time to wait for all nodes to be ready and the core workloads in kube-system to be available.
+Use 0 to skip the check, e.g. if the API server can't be reached directly.
+Private clusters are only waited for if it's set explicitly, since their API server is only reachable through a tunnel.`,
+	)
+}
+
+// waitTimeoutFor returns the time to wait for the cluster with the given args to become ready.
+// The kubeconfig of a private cluster points to a private IP, so it's only waited for if the flag is set explicitly.
+func waitTimeoutFor(cmd *cobra.Command, timeout time.Duration, args *k3s.ClusterArgs) time.Duration {
+	if args.Public || cmd.Flags().Changed(waitTimeoutFlag) {
+		return timeout
+	}
+
+	return 0
+}
+
+// logWaitHint tells how to wait for a private cluster whose readiness wasn't checked.
+func logWaitHint(manager *kindacool.Manager, timeout time.Duration, args *k3s.ClusterArgs) {
+	if timeout > 0 || args.Public {
+		return
+	}
+
+	manager.LogStage(kindacool.StageWait, fmt.Sprintf(
+		"Skipped waiting for the private cluster, run '%[1]s cluster tunnel' and '%[1]s cluster wait --kubeconfig <tunnel kubeconfig>' "+
+			"to wait until it's ready",
+		CLI,
+	))
+}
+
+// rotatePrefix uses a random emoji as prefix for the logger until the context is done.
+func rotatePrefix(ctx context.Context, logger *log.Logger) {
+	emojis := []string{"🚀", "💃", "✨", "🔥", "🦥", "👽", "👾", "👀", "💅"}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(outputPrefixChangeInterval):
+		}
+		//nolint:gosec // not relevant for security
+		logger.SetPrefix(emojis[rand.Intn(len(emojis)-1)] + " ")
+	}
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/brumhard/kindacool/pkg/kindacool"
+
+	"github.com/spf13/cobra"
+)
+
+func BuildCancelCommand(manager *kindacool.Manager) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cancel",
+		Short: "Cancel a running update of a cluster",
+		Long: fmt.Sprintf(`The cancel command stops the update that is currently running for a cluster.
+
+This is useful if a create or destroy hangs or the process was killed.
+Canceling might leave operations pending that block following updates.
+These are listed afterwards and can be removed with:
+	$ %s cluster unlock`, CLI),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			operations, err := manager.Cancel(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			if len(operations) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "The update was canceled.")
+				return nil
+			}
+
+			fmt.Fprintf(
+				cmd.OutOrStdout(),
+				"The update was canceled, but some operations are still pending. Run '%s cluster unlock' to remove them.\n\n",
+				CLI,
+			)
+			return printPendingOperations(cmd.OutOrStdout(), operations)
+		},
+	}
+
+	return cmd
+}
+
+func BuildUnlockCommand(manager *kindacool.Manager) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unlock",
+		Short: "Remove pending operations from a cluster's state",
+		Long: `The unlock command removes all pending operations from a cluster's state.
+
+Pending operations remain if an update was canceled or the process was killed and prevent further updates.
+Resources that were still being created might exist in OpenStack without being tracked anymore.
+These are listed and should be checked and cleaned up manually if needed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			operations, err := manager.Unlock(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			if len(operations) == 0 {
+				return nil
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), "Removed the following pending operations:")
+			return printPendingOperations(cmd.OutOrStdout(), operations)
+		},
+	}
+
+	return cmd
+}
+
+func printPendingOperations(w io.Writer, operations []kindacool.PendingOperation) error {
+	//nolint:gomnd // padding between columns
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "OPERATION\tTYPE\tNAME\tID\tNOTE")
+	for _, operation := range operations {
+		note := ""
+		if operation.HalfCreated() {
+			note = "might be half-created, check OpenStack"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", operation.Op, operation.Type, operation.Name, operation.ID, note)
+	}
+
+	return tw.Flush()
+}
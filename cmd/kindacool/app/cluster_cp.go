@@ -0,0 +1,134 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/brumhard/kindacool/pkg/k3s"
+	"github.com/brumhard/kindacool/pkg/kindacool"
+
+	"github.com/spf13/cobra"
+)
+
+var ErrCopyFailed = errors.New("failed to copy files")
+
+func BuildCopyCommand(manager *kindacool.Manager) *cobra.Command {
+	var (
+		sshOpts   kindacool.SSHOptions
+		selector  string
+		recursive bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "cp SOURCE DESTINATION",
+		Short: "Copy files to and from the nodes of the cluster",
+		Long: fmt.Sprintf(`The cp command copies files between the local machine and the nodes of the cluster using SFTP.
+
+Paths on a node are given as <node>:<path> with the node's name or index.
+If the node is omitted like :<path>, the nodes selected with --nodes or the server node are used.
+Local paths that contain a colon have to start with ./ or /.
+
+	# copy a file to all worker nodes
+	$ %[1]s cluster cp ./config.toml :/tmp/config.toml --nodes role=worker
+
+	# copy a directory from the second node
+	$ %[1]s cluster cp -r 1:/var/log/pods ./pods
+
+When copying from multiple nodes the files of every node are written to a subdirectory with the node's name.
+Files are copied as the machine user, so use 'cluster exec' to move them to locations that require root.`, CLI),
+		Args: cobra.ExactArgs(2), //nolint:gomnd // source and destination
+		RunE: func(cmd *cobra.Command, args []string) error {
+			srcNode, srcPath, srcRemote := parseCopyTarget(args[0])
+			dstNode, dstPath, dstRemote := parseCopyTarget(args[1])
+			if srcRemote == dstRemote {
+				return fmt.Errorf("%w: either the source or the destination has to be on a node like <node>:<path>", ErrInvalidUsage)
+			}
+
+			access, err := manager.NodeAccess(cmd.Context(), sshOpts)
+			if err != nil {
+				return err
+			}
+
+			nodes, err := copyNodes(access, srcNode+dstNode, selector)
+			if err != nil {
+				return err
+			}
+
+			var results []kindacool.CopyResult
+			if dstRemote {
+				results = access.Upload(cmd.Context(), nodes, srcPath, dstPath, recursive)
+			} else {
+				results = access.Download(cmd.Context(), nodes, srcPath, dstPath, recursive)
+			}
+
+			return printCopyResults(cmd.ErrOrStderr(), results)
+		},
+	}
+
+	addSSHFlags(cmd, &sshOpts)
+	cmd.Flags().StringVar(
+		&selector,
+		"nodes", "",
+		"Copy from or to all nodes that match the selector, e.g. role=worker. Can't be used with <node>:<path>.",
+	)
+	cmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Copy directories recursively.")
+
+	return cmd
+}
+
+// parseCopyTarget checks whether the argument is a path on a node like <node>:<path>.
+func parseCopyTarget(arg string) (string, string, bool) {
+	if strings.HasPrefix(arg, "/") || strings.HasPrefix(arg, ".") {
+		return "", arg, false
+	}
+
+	node, nodePath, found := strings.Cut(arg, ":")
+	if !found {
+		return "", arg, false
+	}
+
+	return node, nodePath, true
+}
+
+func copyNodes(access *kindacool.NodeAccess, nodeRef, selector string) ([]k3s.Node, error) {
+	if selector == "" {
+		node, err := access.Node(nodeRef)
+		if err != nil {
+			return nil, err
+		}
+
+		return []k3s.Node{node}, nil
+	}
+
+	if nodeRef != "" {
+		return nil, fmt.Errorf("%w: --nodes can't be used together with <node>:<path>", ErrInvalidUsage)
+	}
+
+	nodeSelector, err := kindacool.ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	return access.SelectNodes(nodeSelector)
+}
+
+func printCopyResults(w io.Writer, results []kindacool.CopyResult) error {
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(w, "✗ %s: %v\n", result.Node.Name, result.Err)
+			continue
+		}
+
+		fmt.Fprintf(w, "✓ %s\n", result.Node.Name)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d nodes failed", ErrCopyFailed, failed, len(results))
+	}
+
+	return nil
+}
@@ -1,6 +1,8 @@
 package app
 
 import (
+	"errors"
+	"fmt"
 	"os"
 
 	"github.com/brumhard/kindacool/pkg/k3s"
@@ -10,7 +12,13 @@ import (
 )
 
 func BuildCreateCommand(manager *kindacool.Manager) *cobra.Command {
-	clusterArgs := &k3s.ClusterArgs{}
+	var (
+		defaults    = kindacool.DefaultClusterArgs()
+		clusterArgs = &k3s.ClusterArgs{}
+		runOpts     = kindacool.RunOptions{}
+		mergeOpts   = KubeconfigMergeOptions{}
+		dryRun      bool
+	)
 
 	cmd := &cobra.Command{
 		Use:   "create",
@@ -20,23 +28,38 @@ func BuildCreateCommand(manager *kindacool.Manager) *cobra.Command {
 It will first create all the required resources like a VM and security groups on OpenStack
 and then install k3s on top of it.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := manager.Run(cmd.Context(), clusterArgs); err != nil {
-				return err
+			if dryRun {
+				plan, err := manager.Preview(cmd.Context(), clusterArgs)
+				if err != nil {
+					return err
+				}
+
+				return printPlan(cmd.OutOrStdout(), plan)
 			}
 
-			kubeconfig, err := manager.FetchOutput(cmd.Context(), kindacool.OutputKubeconfig)
-			if err != nil {
-				return err
+			runOpts.WaitTimeout = waitTimeoutFor(cmd, runOpts.WaitTimeout, clusterArgs)
+
+			// the kubeconfig is still written if the cluster isn't ready, to be able to investigate
+			runErr := manager.Run(cmd.Context(), clusterArgs, runOpts)
+			if runErr != nil && !errors.Is(runErr, kindacool.ErrNotReady) {
+				return runErr
 			}
 
-			kubeconfigFile, err := KubeconfigFile(manager.Options.Name)
-			if err != nil {
+			if err := writeKubeconfig(cmd, manager, runErr); err != nil {
 				return err
 			}
 
-			cmd.Printf("Writing kubeconfig to %q\n", kubeconfigFile)
-			//nolint:gomnd // well-known file permissions
-			return os.WriteFile(kubeconfigFile, []byte(kubeconfig), 0600)
+			if runErr != nil {
+				return runErr
+			}
+
+			logWaitHint(manager, runOpts.WaitTimeout, clusterArgs)
+
+			if !mergeOpts.Merge {
+				return nil
+			}
+
+			return mergeKubeconfig(cmd, manager, mergeOpts)
 		},
 	}
 
@@ -50,7 +73,7 @@ The flag can be defined multiple times like -p 1234 -p 2345`,
 
 	cmd.Flags().StringVarP(
 		&clusterArgs.MachineFlavor,
-		"flavor", "f", "m4.large",
+		"flavor", "f", defaults.MachineFlavor,
 		"OpenStack flavor to be used for the machines. Use 'openstack flavor list' to obtain a list of all flavors.",
 	)
 
@@ -62,7 +85,7 @@ The flag can be defined multiple times like -p 1234 -p 2345`,
 
 	cmd.Flags().IntVarP(
 		&clusterArgs.NodeCount,
-		"nodeCount", "c", 1,
+		"nodeCount", "c", defaults.NodeCount,
 		`Amount of nodes to create and join to a cluster.
 If the count is >1 additional worker nodes will be joined to a single master node.`,
 	)
@@ -71,18 +94,18 @@ If the count is >1 additional worker nodes will be joined to a single master nod
 		&clusterArgs.VolumeSize,
 		"volumeSize", 0,
 		`Size in GigaBytes (GB) that will be added to the boot volume.
-If the size is <=0 no additional volume will be created.`,
+If the size is 0 no additional volume will be created.`,
 	)
 
 	cmd.Flags().StringVar(
 		&clusterArgs.MachineImage,
-		"machineImage", "Ubuntu 22.04",
+		"machineImage", defaults.MachineImage,
 		"Openstack image that will be used for the nodes. Use 'openstack image list' to obtain a list of all images.",
 	)
 
 	cmd.Flags().StringVar(
 		&clusterArgs.MachineUser,
-		"machineUser", "ubuntu",
+		"machineUser", defaults.MachineUser,
 		"User that sets up k3s via SSH.",
 	)
 
@@ -110,5 +133,98 @@ If the size is <=0 no additional volume will be created.`,
 		"Network ID that is exposed to the internet.",
 	)
 
+	cmd.Flags().IntVar(
+		&clusterArgs.SSHDialTimeout,
+		"sshDialTimeout", 0,
+		`Timeout in seconds of a single SSH connection attempt to install k3s on the nodes.
+If it's 0 the default of pulumi's command provider (15s) is used.`,
+	)
+
+	cmd.Flags().IntVar(
+		&clusterArgs.SSHDialRetries,
+		"sshDialRetries", 0,
+		`Number of failed SSH connection attempts before installing k3s fails, e.g. while the nodes are still booting.
+If it's 0 the default of pulumi's command provider (10) is used, -1 retries until the nodes can be reached.`,
+	)
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, dryRunFlagUsage)
+	addRetriesFlag(cmd, &runOpts.Retries)
+	addWaitTimeoutFlag(cmd, &runOpts.WaitTimeout)
+	cmd.Flags().BoolVar(&runOpts.SkipPreflight, "skip-preflight", false, skipPreflightFlagUsage)
+
+	cmd.Flags().StringToStringVarP(
+		&runOpts.Tags,
+		"tag", "t", nil,
+		`Tags to add to the cluster that can be used to select it in other commands.
+The flag can be defined multiple times like -t team=infra -t ttl-expired=`,
+	)
+
+	addKubeconfigMergeFlags(cmd, &mergeOpts, "merge-kubeconfig")
+
+	registerCreateCompletions(cmd)
+
 	return cmd
 }
+
+// writeKubeconfig fetches the kubeconfig of the manager's cluster and writes it to the default location.
+// In JSON log format the cluster is also added to the result document, as failed if runErr is set.
+func writeKubeconfig(cmd *cobra.Command, manager *kindacool.Manager, runErr error) error {
+	kubeconfig, err := manager.FetchOutput(cmd.Context(), kindacool.OutputKubeconfig)
+	if err != nil {
+		return err
+	}
+
+	kubeconfigFile, err := KubeconfigFile(manager.Options.Name)
+	if err != nil {
+		return err
+	}
+
+	manager.Logger.Printf("Writing kubeconfig to %q\n", kubeconfigFile)
+	//nolint:gomnd // well-known file permissions
+	if err := os.WriteFile(kubeconfigFile, []byte(kubeconfig), 0600); err != nil {
+		return err
+	}
+
+	if !jsonOutput(cmd) {
+		return nil
+	}
+
+	description, err := manager.Describe(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	clusterResult := ClusterResult{
+		Name:           manager.Options.Name,
+		Status:         kindacool.StatusSucceeded,
+		KubeconfigPath: kubeconfigFile,
+		Nodes:          description.Nodes,
+	}
+	if runErr != nil {
+		clusterResult.Status = kindacool.StatusFailed
+		clusterResult.Error = runErr.Error()
+	}
+	recordCluster(cmd, clusterResult)
+
+	return nil
+}
+
+// mergeKubeconfig fetches the kubeconfig of the manager's cluster and merges it into the default kubeconfig.
+func mergeKubeconfig(cmd *cobra.Command, manager *kindacool.Manager, opts KubeconfigMergeOptions) error {
+	kubeconfig, err := manager.FetchOutput(cmd.Context(), kindacool.OutputKubeconfig)
+	if err != nil {
+		return err
+	}
+
+	contextName, err := MergeKubeconfig([]byte(kubeconfig), manager.Options.Name, opts)
+	if err != nil {
+		return fmt.Errorf("failed to merge kubeconfig: %w", err)
+	}
+
+	manager.Logger.Printf("Merged kubeconfig as context %q\n", contextName)
+	if opts.SwitchContext {
+		manager.Logger.Printf("Switched to context %q\n", contextName)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/brumhard/kindacool/pkg/kindacool"
+
+	"github.com/spf13/cobra"
+)
+
+func BuildDescribeCommand(manager *kindacool.Manager) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "describe",
+		Short: "Show the details of a cluster",
+		Long: `The describe command shows everything that makes up a cluster.
+
+This includes the arguments it was created with, all the nodes with their addresses,
+the installed k3s version, the security group rules, the network IDs and the result of the last update.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			description, err := manager.Describe(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			return printOutput(cmd.OutOrStdout(), output, description, func(w io.Writer) {
+				printDescriptionTable(w, description)
+			})
+		},
+	}
+
+	addOutputFlag(cmd, &output, outputTable, outputJSON, outputYAML)
+
+	return cmd
+}
+
+func printDescriptionTable(w io.Writer, description *kindacool.ClusterDescription) {
+	fmt.Fprintf(w, "Name:\t%s\n", description.Name)
+	fmt.Fprintf(w, "K3s Version:\t%s\n", description.K3sVersion)
+	if cloud := description.Cloud; cloud != nil {
+		fmt.Fprintf(w, "Cloud:\t%s\n", cloud.Cloud)
+		fmt.Fprintf(w, "Region:\t%s\n", cloud.Region)
+		fmt.Fprintf(w, "Auth URL:\t%s\n", cloud.AuthURL)
+		fmt.Fprintf(w, "Project ID:\t%s\n", cloud.ProjectID)
+	}
+	if args := description.Args; args != nil {
+		fmt.Fprintf(w, "Flavor:\t%s\n", args.MachineFlavor)
+		fmt.Fprintf(w, "Image:\t%s\n", args.MachineImage)
+		fmt.Fprintf(w, "User:\t%s\n", args.MachineUser)
+		fmt.Fprintf(w, "Node Count:\t%d\n", args.NodeCount)
+		fmt.Fprintf(w, "Volume Size:\t%d\n", args.VolumeSize)
+		fmt.Fprintf(w, "Public:\t%t\n", args.Public)
+	}
+	fmt.Fprintf(w, "Last Update:\t%s (%s)\n", description.LastUpdate, description.LastResult)
+
+	fmt.Fprintln(w, "\nNODE\tROLE\tPRIVATE IP\tFLOATING IP")
+	for _, node := range description.Nodes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", node.Name, node.Role, node.PrivateIP, node.FloatingIP)
+	}
+
+	fmt.Fprintln(w, "\nPORT\tPROTOCOL\tDESCRIPTION")
+	rules := description.SecurityGroupRules
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Port < rules[j].Port })
+	for _, rule := range rules {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", rule.Port, strings.ToUpper(rule.Protocol), rule.Description)
+	}
+
+	fmt.Fprintln(w, "\nNETWORK\tID")
+	networks := make([]string, 0, len(description.NetworkIDs))
+	for network := range description.NetworkIDs {
+		networks = append(networks, network)
+	}
+	sort.Strings(networks)
+	for _, network := range networks {
+		fmt.Fprintf(w, "%s\t%s\n", network, description.NetworkIDs[network])
+	}
+}
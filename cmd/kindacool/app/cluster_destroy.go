@@ -1,35 +1,256 @@
 package app
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/brumhard/kindacool/pkg/kindacool"
 
 	"github.com/spf13/cobra"
 )
 
+const defaultDestroyParallelism = 4
+
+var (
+	ErrDestroyFailed = errors.New("failed to destroy clusters")
+	ErrAborted       = errors.New("aborted")
+)
+
 func BuildDestroyCommand(manager *kindacool.Manager) *cobra.Command {
+	var (
+		dryRun      bool
+		all         bool
+		selector    string
+		names       []string
+		yes         bool
+		parallelism int
+	)
+
 	cmd := &cobra.Command{
 		Use:     "destroy",
 		Aliases: []string{"rm"},
 		Short:   "Destroys a k3s cluster on OpenStack",
-		Long:    "Destroys a k3s cluster on OpenStack",
+		Long: fmt.Sprintf(`Destroys a k3s cluster on OpenStack
+
+Multiple clusters can be destroyed at once by setting --name multiple times,
+by selecting them by their tags with --selector or by destroying all clusters with --all.
+The clusters are then destroyed in parallel and a summary is shown at the end:
+	$ %s cluster destroy --selector owner=alice,ttl-expired`, CLI),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := manager.Destroy(cmd.Context()); err != nil {
-				return err
+			clusters := names
+			if all || selector != "" {
+				parsedSelector, err := kindacool.ParseSelector(selector)
+				if err != nil {
+					return err
+				}
+
+				selected, err := manager.List(cmd.Context(), parsedSelector)
+				if err != nil {
+					return err
+				}
+
+				clusters = nil
+				if cmd.Flags().Changed("name") {
+					clusters = append(clusters, names...)
+				}
+
+				for _, cluster := range selected {
+					clusters = append(clusters, cluster.Name)
+				}
 			}
 
-			kubeconfigFile, err := KubeconfigFile(manager.Options.Name)
-			if err != nil {
-				return err
+			// a cluster that is named and selected or named multiple times is only destroyed once
+			clusters = uniqueNames(clusters)
+
+			if len(clusters) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No clusters found.")
+				return nil
+			}
+
+			if dryRun {
+				return previewDestroy(cmd, manager, clusters)
 			}
 
-			// ignore errors since they will only occur if the file is not there
-			_ = os.Remove(kubeconfigFile)
+			if len(clusters) == 1 && !all && selector == "" {
+				manager.Options.Name = clusters[0]
+				return destroy(cmd, manager)
+			}
 
-			return nil
+			return destroyClusters(cmd, manager, clusters, yes, parallelism)
 		},
 	}
 
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, dryRunFlagUsage)
+	cmd.Flags().BoolVar(&all, "all", false, "Destroy all clusters.")
+	cmd.Flags().StringVarP(
+		&selector,
+		"selector", "l", "",
+		"Destroy all clusters with matching tags, e.g. owner=alice,ttl-expired. Keys without value only need to exist.",
+	)
+	// shadows the persistent --name flag of the cluster command to allow multiple values
+	cmd.Flags().StringSliceVarP(
+		&names,
+		"name", "n", []string{"kindacool"},
+		"Name of the clusters to destroy. The flag can be defined multiple times like -n a -n b",
+	)
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip the confirmation when destroying multiple clusters.")
+	cmd.Flags().IntVar(
+		&parallelism,
+		"parallel", defaultDestroyParallelism,
+		"Maximum amount of clusters that are destroyed at the same time.",
+	)
+	cmd.MarkFlagsMutuallyExclusive("all", "selector")
+
+	_ = cmd.RegisterFlagCompletionFunc("name", completeClusterNames)
+
 	return cmd
 }
+
+func destroy(cmd *cobra.Command, manager *kindacool.Manager) error {
+	if err := manager.Destroy(cmd.Context()); err != nil {
+		recordCluster(cmd, ClusterResult{Name: manager.Options.Name, Status: kindacool.StatusFailed, Error: err.Error()})
+		return err
+	}
+
+	if err := removeKubeconfig(manager.Options.Name); err != nil {
+		return err
+	}
+
+	recordCluster(cmd, ClusterResult{Name: manager.Options.Name, Status: kindacool.StatusSucceeded})
+
+	return nil
+}
+
+// destroyClusters destroys all given clusters in parallel after the user confirmed it.
+func destroyClusters(cmd *cobra.Command, manager *kindacool.Manager, clusters []string, yes bool, parallelism int) error {
+	if !yes {
+		confirmed, err := confirm(
+			cmd.InOrStdin(), cmd.OutOrStdout(),
+			fmt.Sprintf("The following clusters will be destroyed:\n  %s\n", strings.Join(clusters, "\n  ")),
+		)
+		if err != nil {
+			return err
+		}
+
+		if !confirmed {
+			return ErrAborted
+		}
+	}
+
+	results := manager.DestroyMany(cmd.Context(), clusters, parallelism)
+
+	// the summary is part of the result document in JSON log format
+	summary := cmd.OutOrStdout()
+	if jsonOutput(cmd) {
+		summary = io.Discard
+	}
+
+	failed := 0
+	fmt.Fprintln(summary)
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(summary, "✗ %s: %v\n", result.Name, result.Err)
+			recordCluster(cmd, ClusterResult{Name: result.Name, Status: kindacool.StatusFailed, Error: result.Err.Error()})
+			continue
+		}
+
+		if err := removeKubeconfig(result.Name); err != nil {
+			failed++
+			fmt.Fprintf(summary, "✗ %s: %v\n", result.Name, err)
+			recordCluster(cmd, ClusterResult{Name: result.Name, Status: kindacool.StatusFailed, Error: err.Error()})
+			continue
+		}
+
+		fmt.Fprintf(summary, "✓ %s\n", result.Name)
+		recordCluster(cmd, ClusterResult{Name: result.Name, Status: kindacool.StatusSucceeded})
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d failed", ErrDestroyFailed, failed, len(results))
+	}
+
+	return nil
+}
+
+func previewDestroy(cmd *cobra.Command, manager *kindacool.Manager, clusters []string) error {
+	var pendingErr error
+	for _, cluster := range clusters {
+		clusterManager := manager
+		if len(clusters) > 1 {
+			clusterManager = manager.ForCluster(cluster)
+		} else {
+			manager.Options.Name = cluster
+		}
+
+		plan, err := clusterManager.PreviewDestroy(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		if len(clusters) > 1 {
+			fmt.Fprintf(cmd.OutOrStdout(), "\nCluster %q:\n", cluster)
+		}
+
+		if err := printPlan(cmd.OutOrStdout(), plan); err != nil {
+			pendingErr = err
+		}
+	}
+
+	return pendingErr
+}
+
+// uniqueNames removes duplicates from the names and keeps the order of their first occurrence.
+func uniqueNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	unique := make([]string, 0, len(names))
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+
+	return unique
+}
+
+// removeKubeconfig removes the cluster's kubeconfig file and its merged entries in the default kubeconfig.
+func removeKubeconfig(clusterName string) error {
+	kubeconfigFile, err := KubeconfigFile(clusterName)
+	if err != nil {
+		return err
+	}
+
+	tunnelKubeconfigFile, err := TunnelKubeconfigFile(clusterName)
+	if err != nil {
+		return err
+	}
+
+	// ignore errors since they will only occur if the files are not there
+	_ = os.Remove(kubeconfigFile)
+	_ = os.Remove(tunnelKubeconfigFile)
+
+	if err := RemoveMergedKubeconfig(clusterName); err != nil {
+		return fmt.Errorf("failed to remove merged kubeconfig entries: %w", err)
+	}
+
+	return nil
+}
+
+// confirm shows the message and asks the user to confirm it.
+func confirm(in io.Reader, out io.Writer, message string) (bool, error) {
+	fmt.Fprintf(out, "%sDo you want to continue? [y/N] ", message)
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes", nil
+}
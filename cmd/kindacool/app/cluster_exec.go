@@ -0,0 +1,211 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/brumhard/kindacool/pkg/k3s"
+	"github.com/brumhard/kindacool/pkg/kindacool"
+
+	"github.com/spf13/cobra"
+)
+
+func BuildExecCommand(manager *kindacool.Manager) *cobra.Command {
+	var (
+		sshOpts  kindacool.SSHOptions
+		selector string
+		group    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "exec -- command",
+		Short: "Run a command on the nodes of the cluster",
+		Long: fmt.Sprintf(`The exec command runs a command on all selected nodes of the cluster at the same time.
+
+Nodes can be selected by their name and role, by default all nodes are used:
+	$ %s cluster exec --nodes role=worker -- df -h
+
+Every output line is prefixed with the node's name. Use --group to print the output of every node at once instead.
+The command fails if the command fails on any node.`, CLI),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nodeArgs, command := splitAtDash(cmd, args)
+			if len(nodeArgs) > 0 || len(command) == 0 {
+				return fmt.Errorf("%w: the command has to be given after '--'", ErrInvalidUsage)
+			}
+
+			nodeSelector, err := kindacool.ParseSelector(selector)
+			if err != nil {
+				return err
+			}
+
+			access, err := manager.NodeAccess(cmd.Context(), sshOpts)
+			if err != nil {
+				return err
+			}
+
+			nodes, err := access.SelectNodes(nodeSelector)
+			if err != nil {
+				return err
+			}
+
+			outputs := newNodeOutputs(cmd.OutOrStdout(), cmd.ErrOrStderr(), nodes, group)
+			results := access.Exec(cmd.Context(), nodes, strings.Join(command, " "), outputs.writers)
+			outputs.flush()
+
+			return printExecResults(cmd.ErrOrStderr(), results)
+		},
+	}
+
+	addSSHFlags(cmd, &sshOpts)
+	cmd.Flags().StringVar(
+		&selector,
+		"nodes", "",
+		"Run the command only on the nodes that match the selector, e.g. role=worker or name=kindacool-1.",
+	)
+	cmd.Flags().BoolVar(&group, "group", false, "Print the output of every node at once after all nodes finished.")
+
+	return cmd
+}
+
+func printExecResults(w io.Writer, results []kindacool.ExecResult) error {
+	failed := 0
+	fmt.Fprintln(w)
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(w, "✗ %s (exit %d): %v\n", result.Node.Name, result.ExitCode, result.Err)
+			continue
+		}
+
+		fmt.Fprintf(w, "✓ %s (exit %d)\n", result.Node.Name, result.ExitCode)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d nodes failed", ErrRemoteCommandFailed, failed, len(results))
+	}
+
+	return nil
+}
+
+// nodeOutputs provides the writers for the output of multiple nodes.
+// The lines are either prefixed with the node's name or collected per node.
+type nodeOutputs struct {
+	mu     sync.Mutex
+	stdout io.Writer
+	stderr io.Writer
+	group  bool
+	width  int
+	groups map[string]*bytes.Buffer
+	order  []string
+	open   []*prefixWriter
+}
+
+func newNodeOutputs(stdout, stderr io.Writer, nodes []k3s.Node, group bool) *nodeOutputs {
+	outputs := &nodeOutputs{
+		stdout: stdout,
+		stderr: stderr,
+		group:  group,
+		groups: map[string]*bytes.Buffer{},
+	}
+
+	for _, node := range nodes {
+		if len(node.Name) > outputs.width {
+			outputs.width = len(node.Name)
+		}
+	}
+
+	return outputs
+}
+
+func (o *nodeOutputs) writers(node k3s.Node) (io.Writer, io.Writer) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.group {
+		// stdout and stderr are combined to keep their order
+		buffer := &bytes.Buffer{}
+		o.groups[node.Name] = buffer
+		o.order = append(o.order, node.Name)
+
+		return &lockedWriter{mu: &o.mu, w: buffer}, &lockedWriter{mu: &o.mu, w: buffer}
+	}
+
+	prefix := fmt.Sprintf("[%-*s] ", o.width, node.Name)
+	stdout := &prefixWriter{mu: &o.mu, w: o.stdout, prefix: prefix}
+	stderr := &prefixWriter{mu: &o.mu, w: o.stderr, prefix: prefix}
+	o.open = append(o.open, stdout, stderr)
+
+	return stdout, stderr
+}
+
+// flush writes the collected output of all nodes or the remaining partial lines.
+func (o *nodeOutputs) flush() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, w := range o.open {
+		w.flush()
+	}
+
+	for _, name := range o.order {
+		output := o.groups[name].Bytes()
+		if len(output) > 0 && output[len(output)-1] != '\n' {
+			output = append(output, '\n')
+		}
+
+		fmt.Fprintf(o.stdout, "=== %s ===\n%s", name, output)
+	}
+}
+
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.w.Write(p)
+}
+
+// prefixWriter writes every complete line with the prefix.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buffer []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buffer = append(w.buffer, p...)
+	for {
+		i := bytes.IndexByte(w.buffer, '\n')
+		if i < 0 {
+			break
+		}
+
+		if _, err := fmt.Fprintf(w.w, "%s%s", w.prefix, w.buffer[:i+1]); err != nil {
+			return 0, err
+		}
+		w.buffer = w.buffer[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// flush writes the last line if it didn't end with a newline. The caller has to hold the lock.
+func (w *prefixWriter) flush() {
+	if len(w.buffer) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w.w, "%s%s\n", w.prefix, w.buffer)
+	w.buffer = nil
+}
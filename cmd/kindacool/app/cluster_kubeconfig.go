@@ -9,7 +9,10 @@ import (
 )
 
 func BuildKubeconfigCommand(manager *kindacool.Manager) *cobra.Command {
-	var export bool
+	var (
+		export    bool
+		mergeOpts KubeconfigMergeOptions
+	)
 
 	cmd := &cobra.Command{
 		Use:   "kubeconfig",
@@ -22,6 +25,10 @@ func BuildKubeconfigCommand(manager *kindacool.Manager) *cobra.Command {
 2. If the --export flag is set it will instead output a shell command to set $KUBECONFIG to the cluster's kubeconfig's default file path.
    This can be used like:
    	$ eval "$(%[1]s cluster kubeconfig --export)"
+
+3. If the --merge flag is set the cluster, user and context are merged into the default kubeconfig
+   ($KUBECONFIG or ~/.kube/config) instead. The entries are removed again when the cluster is destroyed.
+   	$ %[1]s cluster kubeconfig --merge --context my-cluster --switch-context
 `, CLI),
 
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -35,6 +42,10 @@ func BuildKubeconfigCommand(manager *kindacool.Manager) *cobra.Command {
 				return nil
 			}
 
+			if mergeOpts.Merge {
+				return mergeKubeconfig(cmd, manager, mergeOpts)
+			}
+
 			kubeconfig, err := manager.FetchOutput(cmd.Context(), kindacool.OutputKubeconfig)
 			if err != nil {
 				return err
@@ -46,6 +57,8 @@ func BuildKubeconfigCommand(manager *kindacool.Manager) *cobra.Command {
 	}
 
 	cmd.Flags().BoolVar(&export, "export", false, "Output an export string pointing to the file instead of the kubeconfig itself.")
+	addKubeconfigMergeFlags(cmd, &mergeOpts, "merge")
+	cmd.MarkFlagsMutuallyExclusive("export", "merge")
 
 	return cmd
 }
@@ -2,28 +2,72 @@ package app
 
 import (
 	"fmt"
-	"strings"
+	"io"
 
 	"github.com/brumhard/kindacool/pkg/kindacool"
 
 	"github.com/spf13/cobra"
 )
 
+const outputName = "name"
+
 func BuildLsCommand(manager *kindacool.Manager) *cobra.Command {
+	var (
+		output   string
+		selector string
+		owner    string
+	)
+
 	cmd := &cobra.Command{
 		Use:   "ls",
 		Short: "List all k3s clusters on OpenStack",
-		Long:  "",
+		Long: fmt.Sprintf(`The ls command lists all clusters with their most important properties.
+
+The clusters can be filtered by their tags with --selector and by the user that created them with --owner.
+To only output the names, e.g. for usage in scripts, use -o name:
+	$ %s cluster ls -o name`, CLI),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			clusters, err := manager.List(cmd.Context())
+			parsedSelector, err := kindacool.ParseSelector(selector)
 			if err != nil {
 				return err
 			}
 
-			fmt.Fprintln(cmd.OutOrStdout(), strings.Join(clusters, "\n"))
-			return nil
+			if owner != "" {
+				parsedSelector[kindacool.TagOwner] = owner
+			}
+
+			clusters, err := manager.List(cmd.Context(), parsedSelector)
+			if err != nil {
+				return err
+			}
+
+			if output == outputName {
+				for _, cluster := range clusters {
+					fmt.Fprintln(cmd.OutOrStdout(), cluster.Name)
+				}
+
+				return nil
+			}
+
+			return printOutput(cmd.OutOrStdout(), output, clusters, func(w io.Writer) {
+				fmt.Fprintln(w, "NAME\tNODES\tPUBLIC\tK3S VERSION\tLAST UPDATE\tSTATUS\tRESOURCES\tOWNER")
+				for _, c := range clusters {
+					fmt.Fprintf(
+						w, "%s\t%d\t%t\t%s\t%s\t%s\t%d\t%s\n",
+						c.Name, c.NodeCount, c.Public, c.K3sVersion, c.LastUpdate, c.Status, c.ResourceCount, c.Owner,
+					)
+				}
+			})
 		},
 	}
 
+	addOutputFlag(cmd, &output, outputTable, outputJSON, outputYAML, outputName)
+	cmd.Flags().StringVarP(
+		&selector,
+		"selector", "l", "",
+		"Only list clusters with matching tags, e.g. owner=alice,ttl-expired. Keys without value only need to exist.",
+	)
+	cmd.Flags().StringVar(&owner, "owner", "", "Only list clusters created by the given user.")
+
 	return cmd
 }
@@ -0,0 +1,147 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/brumhard/kindacool/pkg/kindacool"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+)
+
+const defaultTerm = "xterm-256color"
+
+var ErrRemoteCommandFailed = errors.New("remote command failed")
+
+func BuildSSHCommand(manager *kindacool.Manager) *cobra.Command {
+	var sshOpts kindacool.SSHOptions
+
+	cmd := &cobra.Command{
+		Use:   "ssh [node] [-- command]",
+		Short: "Open a shell on a node of the cluster",
+		Long: fmt.Sprintf(`The ssh command opens an interactive shell on a node of the cluster.
+
+The node can be selected by its name or index as shown by '%[1]s cluster describe'.
+By default the server node is used. If a command is given after '--' it is executed instead of a shell:
+	$ %[1]s cluster ssh 1 -- sudo journalctl -u k3s-agent
+
+The cluster's ssh key is used and the nodes are reached by their floating IP.
+For private clusters a bastion can be configured that is used to reach the nodes' private IPs.`, CLI),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nodeArgs, command := splitAtDash(cmd, args)
+			if len(nodeArgs) > 1 {
+				return fmt.Errorf("%w: only a single node can be selected, got %q", ErrInvalidUsage, nodeArgs)
+			}
+
+			nodeRef := ""
+			if len(nodeArgs) == 1 {
+				nodeRef = nodeArgs[0]
+			}
+
+			access, err := manager.NodeAccess(cmd.Context(), sshOpts)
+			if err != nil {
+				return err
+			}
+
+			node, err := access.Node(nodeRef)
+			if err != nil {
+				return err
+			}
+
+			client, err := access.Dial(cmd.Context(), node)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			return runSession(cmd, client, command)
+		},
+	}
+
+	addSSHFlags(cmd, &sshOpts)
+
+	return cmd
+}
+
+func addSSHFlags(cmd *cobra.Command, opts *kindacool.SSHOptions) {
+	cmd.Flags().StringVar(
+		&opts.Bastion,
+		"bastion", "",
+		"Jump host in the format [user@]host[:port] that is used to reach the nodes by their private IP.",
+	)
+	cmd.Flags().StringVar(
+		&opts.BastionKeyFile,
+		"bastion-key", "",
+		"Private key for the bastion. By default the ssh-agent and the cluster's key are used.",
+	)
+}
+
+// splitAtDash splits the args into the ones before and after '--'.
+func splitAtDash(cmd *cobra.Command, args []string) ([]string, []string) {
+	dash := cmd.ArgsLenAtDash()
+	if dash < 0 {
+		return args, nil
+	}
+
+	return args[:dash], args[dash:]
+}
+
+// runSession runs the command on the connected node or opens an interactive shell if no command is given.
+func runSession(cmd *cobra.Command, client *ssh.Client, command []string) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	session.Stdin = cmd.InOrStdin()
+	session.Stdout = cmd.OutOrStdout()
+	session.Stderr = cmd.ErrOrStderr()
+
+	if len(command) > 0 {
+		return remoteCommandError(session.Run(strings.Join(command, " ")))
+	}
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = term.Restore(fd, state) }()
+
+		width, height, err := term.GetSize(fd)
+		if err != nil {
+			return err
+		}
+
+		terminal := os.Getenv("TERM")
+		if terminal == "" {
+			terminal = defaultTerm
+		}
+
+		if err := session.RequestPty(terminal, height, width, ssh.TerminalModes{ssh.ECHO: 1}); err != nil {
+			return err
+		}
+	}
+
+	if err := session.Shell(); err != nil {
+		return err
+	}
+
+	return remoteCommandError(session.Wait())
+}
+
+// remoteCommandError converts the exit status of a remote command into an error.
+func remoteCommandError(err error) error {
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("%w: exit status %d", ErrRemoteCommandFailed, exitErr.ExitStatus())
+	}
+
+	return err
+}
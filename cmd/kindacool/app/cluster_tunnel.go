@@ -0,0 +1,109 @@
+package app
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/brumhard/kindacool/pkg/k3s"
+	"github.com/brumhard/kindacool/pkg/kindacool"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const defaultTunnelPort = 16443
+
+func BuildTunnelCommand(manager *kindacool.Manager) *cobra.Command {
+	var (
+		sshOpts kindacool.SSHOptions
+		address string
+		port    int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "tunnel",
+		Short: "Forward the cluster's API server to a local port",
+		Long: fmt.Sprintf(`The tunnel command makes the API server of a cluster available on a local port.
+
+Clusters that are not public can only be reached from inside the OpenStack network.
+The tunnel forwards the local port to the API server through an SSH connection to the server node,
+which is reached through the bastion if one is configured. If the connection drops it is reestablished.
+
+A kubeconfig pointing to the local port is written to ~/.kube/%[1]s-<clustername>-tunnel.yaml.
+The tunnel keeps running until it is interrupted, use it from another shell like:
+	$ %[1]s cluster tunnel --bastion jump.example.com
+	$ export KUBECONFIG=~/.kube/%[1]s-kindacool-tunnel.yaml`, CLI),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			access, err := manager.NodeAccess(cmd.Context(), sshOpts)
+			if err != nil {
+				return err
+			}
+
+			node, err := access.Node("")
+			if err != nil {
+				return err
+			}
+
+			kubeconfig, err := manager.FetchOutput(cmd.Context(), kindacool.OutputKubeconfig)
+			if err != nil {
+				return err
+			}
+
+			listener, err := net.Listen("tcp", net.JoinHostPort(address, strconv.Itoa(port)))
+			if err != nil {
+				return err
+			}
+			defer listener.Close()
+
+			if err := writeTunnelKubeconfig(manager, []byte(kubeconfig), listener.Addr().String()); err != nil {
+				return err
+			}
+
+			return access.Tunnel(cmd.Context(), node, listener, k3s.KubeAPIServerPort, manager.Logger)
+		},
+	}
+
+	addSSHFlags(cmd, &sshOpts)
+	cmd.Flags().StringVar(&address, "address", "127.0.0.1", "Local address to listen on.")
+	cmd.Flags().IntVarP(&port, "port", "p", defaultTunnelPort, "Local port to listen on. If it is 0 a free port is chosen.")
+
+	return cmd
+}
+
+// writeTunnelKubeconfig writes a copy of the cluster's kubeconfig that uses the local address of the tunnel.
+// The API server's certificate is valid for 127.0.0.1, for other addresses the server name is set explicitly.
+func writeTunnelKubeconfig(manager *kindacool.Manager, kubeconfig []byte, address string) error {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	for _, cluster := range config.Clusters {
+		cluster.Server = fmt.Sprintf("https://%s", address)
+		if host != "127.0.0.1" {
+			cluster.TLSServerName = "127.0.0.1"
+		}
+	}
+
+	content, err := clientcmd.Write(*config)
+	if err != nil {
+		return err
+	}
+
+	kubeconfigFile, err := TunnelKubeconfigFile(manager.Options.Name)
+	if err != nil {
+		return err
+	}
+
+	manager.Logger.Printf("Writing kubeconfig for the tunnel to %q\n", kubeconfigFile)
+	//nolint:gomnd // well-known file permissions
+	return os.WriteFile(kubeconfigFile, content, 0600)
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/brumhard/kindacool/pkg/kindacool"
+
+	"github.com/spf13/cobra"
+)
+
+func BuildWaitCommand(manager *kindacool.Manager) *cobra.Command {
+	var (
+		timeout        time.Duration
+		kubeconfigFile string
+		output         string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "wait",
+		Short: "Wait until a cluster is ready",
+		Long: fmt.Sprintf(`The wait command waits until all nodes of a cluster are ready
+and the core workloads in kube-system like CoreDNS are available.
+
+The cluster's kubeconfig is used to connect to the API server.
+If the API server is only reachable through a tunnel, pass the kubeconfig of the tunnel:
+	$ %[1]s cluster tunnel &
+	$ %[1]s cluster wait --kubeconfig ~/.kube/%[1]s-kindacool-tunnel.yaml`, CLI),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var kubeconfig []byte
+			if kubeconfigFile != "" {
+				var err error
+				if kubeconfig, err = os.ReadFile(kubeconfigFile); err != nil {
+					return err
+				}
+			}
+
+			readiness, err := manager.WaitReady(cmd.Context(), kubeconfig, timeout)
+			if err != nil {
+				return err
+			}
+
+			return printOutput(cmd.OutOrStdout(), output, readiness, func(w io.Writer) {
+				fmt.Fprintf(w, "Nodes:\t%d/%d ready\n", readiness.ReadyNodes, readiness.ExpectedNodes)
+				fmt.Fprintf(w, "Workloads:\tavailable\n")
+				if len(readiness.NotReadyNodes) > 0 {
+					fmt.Fprintf(w, "Not ready:\t%s\n", strings.Join(readiness.NotReadyNodes, ", "))
+				}
+			})
+		},
+	}
+
+	cmd.Flags().DurationVar(
+		&timeout, "waitTimeout", kindacool.DefaultWaitTimeout,
+		"Time to wait for all nodes to be ready and the core workloads in kube-system to be available.",
+	)
+	cmd.Flags().StringVar(
+		&kubeconfigFile, "kubeconfig", "",
+		"Kubeconfig to connect to the API server with, e.g. the one of a tunnel. Defaults to the cluster's kubeconfig.",
+	)
+	addOutputFlag(cmd, &output, outputTable, outputJSON, outputYAML)
+
+	return cmd
+}
@@ -0,0 +1,206 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/brumhard/kindacool/pkg/kindacool"
+
+	"github.com/spf13/cobra"
+)
+
+// completionCacheTTL is the time the values for completions are cached
+// to not query OpenStack or the pulumi backend on every key press.
+const completionCacheTTL = 5 * time.Minute
+
+type completionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+func BuildCompletionCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "completion bash|zsh|fish|powershell",
+		Short: "Generate the autocompletion script for the specified shell",
+		Long: fmt.Sprintf(`The completion command generates the autocompletion script for the specified shell.
+
+Cluster names, flavors, images and networks are completed dynamically.
+The values are cached for %[2]s in the user's cache directory.
+
+To load completions in the current shell session:
+	$ source <(%[1]s completion bash)
+	$ source <(%[1]s completion zsh)
+	$ %[1]s completion fish | source
+
+To load completions for every new session add the line to your ~/.bashrc or ~/.zshrc
+or write the output to ~/.config/fish/completions/%[1]s.fish for fish.`, CLI, completionCacheTTL),
+		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := cmd.Root()
+			out := cmd.OutOrStdout()
+
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletionV2(out, true)
+			case "zsh":
+				return root.GenZshCompletion(out)
+			case "fish":
+				return root.GenFishCompletion(out, true)
+			default:
+				return root.GenPowerShellCompletionWithDesc(out)
+			}
+		},
+	}
+
+	return cmd
+}
+
+// completeClusterNames completes the names of all existing clusters.
+func completeClusterNames(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	names, err := cachedCompletion("clusters", kindacool.CloudOptions{}, func() ([]string, error) {
+		manager := &kindacool.Manager{Logger: log.New(io.Discard, "", 0)}
+		return manager.ClusterNames(completionContext(cmd))
+	})
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeFromCloud returns a completion for values that are fetched from OpenStack.
+func completeFromCloud(kind string, list func(cloud *kindacool.Cloud) ([]string, error)) completionFunc {
+	return func(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		options := completionCloud(cmd)
+		values, err := cachedCompletion(kind, options, func() ([]string, error) {
+			cloud, err := kindacool.NewCloud(completionContext(cmd), options)
+			if err != nil {
+				return nil, err
+			}
+
+			return list(cloud)
+		})
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeNetworks returns a completion for the networks of OpenStack.
+// The value can either be the name or the ID of the network.
+func completeNetworks(kind string, external, byID bool) completionFunc {
+	return completeFromCloud(kind, func(cloud *kindacool.Cloud) ([]string, error) {
+		networks, err := cloud.Networks()
+		if err != nil {
+			return nil, err
+		}
+
+		var values []string
+		for _, network := range networks {
+			if network.External != external {
+				continue
+			}
+
+			if byID {
+				values = append(values, fmt.Sprintf("%s\t%s", network.ID, network.Name))
+				continue
+			}
+
+			values = append(values, network.Name)
+		}
+
+		return values, nil
+	})
+}
+
+func registerCreateCompletions(cmd *cobra.Command) {
+	completions := map[string]completionFunc{
+		"flavor":             completeFromCloud("flavors", (*kindacool.Cloud).Flavors),
+		"machineImage":       completeFromCloud("images", (*kindacool.Cloud).Images),
+		"privateNetworkName": completeNetworks("private-networks", false, false),
+		"publicNetworkName":  completeNetworks("public-networks", true, false),
+		"publicNetworkID":    completeNetworks("public-network-ids", true, true),
+		"publicIPPool":       completeNetworks("public-networks", true, false),
+	}
+
+	for flag, completion := range completions {
+		// the flags are defined in the same command, so this can't fail
+		_ = cmd.RegisterFlagCompletionFunc(flag, completion)
+	}
+}
+
+// completeCloudNames completes the clouds of clouds.yaml.
+func completeCloudNames(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	names, err := kindacool.CloudNames()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completionCloud returns the cloud that is selected by the flags of the command.
+func completionCloud(cmd *cobra.Command) kindacool.CloudOptions {
+	options := kindacool.CloudOptions{}
+	if flag := cmd.Flag("cloud"); flag != nil {
+		options.Cloud = flag.Value.String()
+	}
+
+	if flag := cmd.Flag("region"); flag != nil {
+		options.Region = flag.Value.String()
+	}
+
+	return options
+}
+
+func completionContext(cmd *cobra.Command) context.Context {
+	if cmd.Context() != nil {
+		return cmd.Context()
+	}
+
+	return context.Background()
+}
+
+// cachedCompletion returns the cached values of the given kind or lists and caches them.
+// The cache is scoped to the given cloud and the OpenStack project that is configured in the environment.
+func cachedCompletion(kind string, cloud kindacool.CloudOptions, list func() ([]string, error)) ([]string, error) {
+	cacheFile := ""
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		scope := strings.Join([]string{
+			cloud.Cloud, cloud.Region, os.Getenv("OS_CLOUD"),
+			os.Getenv("OS_AUTH_URL"), os.Getenv("OS_PROJECT_ID"), os.Getenv("OS_PROJECT_NAME"), os.Getenv("OS_REGION_NAME"),
+		}, "|")
+		cacheFile = filepath.Join(cacheDir, CLI, "completion", fmt.Sprintf("%s-%x.json", kind, sha256.Sum256([]byte(scope))))
+	}
+
+	if info, err := os.Stat(cacheFile); err == nil && time.Since(info.ModTime()) < completionCacheTTL {
+		var values []string
+		if content, err := os.ReadFile(cacheFile); err == nil && json.Unmarshal(content, &values) == nil {
+			return values, nil
+		}
+	}
+
+	values, err := list()
+	if err != nil {
+		return nil, err
+	}
+
+	// the cache is only an optimization, so errors are ignored
+	if content, err := json.Marshal(values); err == nil && cacheFile != "" {
+		//nolint:gomnd // well-known directory permissions
+		if err := os.MkdirAll(filepath.Dir(cacheFile), 0700); err == nil {
+			//nolint:gomnd // well-known file permissions
+			_ = os.WriteFile(cacheFile, content, 0600)
+		}
+	}
+
+	return values, nil
+}
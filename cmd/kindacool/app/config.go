@@ -0,0 +1,263 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	envPrefix   = "KINDACOOL_"
+	profileFlag = "profile"
+)
+
+const (
+	sourceDefault = "default"
+	sourceFlag    = "flag"
+	sourceEnv     = "env"
+	sourceProfile = "profile"
+)
+
+var (
+	ErrUnknownProfile    = errors.New("profile not found in config file")
+	ErrUnknownProfileKey = errors.New("unknown keys in profile")
+)
+
+// configFlags are the flags that are filled from environment variables and profiles.
+// Flags that select clusters, skip confirmations or change what a command does, like --all, --yes or --dry-run,
+// must only be set explicitly, so that a forgotten variable or profile can't turn a command destructive.
+var configFlags = map[string]bool{
+	// cluster args
+	"additionalPorts":    true,
+	"flavor":             true,
+	"public":             true,
+	"nodeCount":          true,
+	"volumeSize":         true,
+	"machineImage":       true,
+	"machineUser":        true,
+	"privateNetworkName": true,
+	"publicIPPool":       true,
+	"publicNetworkName":  true,
+	"publicNetworkID":    true,
+	"sshDialTimeout":     true,
+	"sshDialRetries":     true,
+	// cloud
+	"cloud":                         true,
+	"region":                        true,
+	"application-credential-id":     true,
+	"application-credential-secret": true,
+	// pulumi CLI and plugins
+	"pulumi-mirror":   true,
+	"pulumi-archive":  true,
+	"pulumi-checksum": true,
+	"plugin-version":  true,
+	"plugin-source":   true,
+	// output
+	logFormatFlag: true,
+}
+
+// UserConfig is the content of the user's config file.
+// Every profile maps flag names to the values that should be used if the flag is not set explicitly.
+type UserConfig struct {
+	CurrentProfile string                            `json:"currentProfile,omitempty"`
+	Profiles       map[string]map[string]interface{} `json:"profiles,omitempty"`
+}
+
+// ConfigFile returns the path to the user's config file.
+// It respects $XDG_CONFIG_HOME and defaults to ~/.config/kindacool/config.yaml.
+func ConfigFile() (string, error) {
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return "", err
+		}
+
+		configDir = path.Join(home, ".config")
+	}
+
+	return path.Join(configDir, CLI, "config.yaml"), nil
+}
+
+// LoadUserConfig reads the user's config file.
+// If the file doesn't exist an empty config is returned.
+func LoadUserConfig() (*UserConfig, error) {
+	configFile, err := ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(configFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return &UserConfig{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	config := &UserConfig{}
+	if err := yaml.UnmarshalStrict(content, config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", configFile, err)
+	}
+
+	return config, nil
+}
+
+// Profile returns the name and the values of the active profile.
+// The profile is selected by the --profile flag, $KINDACOOL_PROFILE or the currentProfile in the config file.
+func (c *UserConfig) Profile(cmd *cobra.Command) (string, map[string]interface{}, error) {
+	name := c.CurrentProfile
+	if env, ok := os.LookupEnv(envVar(profileFlag)); ok {
+		name = env
+	}
+	if flag := cmd.Flags().Lookup(profileFlag); flag != nil && flag.Changed {
+		name = flag.Value.String()
+	}
+
+	if name == "" {
+		return "", nil, nil
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: %q", ErrUnknownProfile, name)
+	}
+
+	if unknown := unknownProfileKeys(profile); len(unknown) > 0 {
+		return "", nil, fmt.Errorf("%w %q: %s", ErrUnknownProfileKey, name, strings.Join(unknown, ", "))
+	}
+
+	return name, profile, nil
+}
+
+// unknownProfileKeys returns the sorted keys of the profile that don't match a config flag,
+// e.g. typos or flags like --yes that must be set explicitly.
+func unknownProfileKeys(profile map[string]interface{}) []string {
+	var unknown []string
+	for key := range profile {
+		if !configFlags[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+
+	return unknown
+}
+
+// applyUserConfig sets the values of the config flags that were not set explicitly
+// from the environment variables or the active profile, in that order.
+// It returns where the value of each flag comes from.
+func applyUserConfig(cmd *cobra.Command) (map[string]string, error) {
+	config, err := LoadUserConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	_, profile, err := config.Profile(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return applyConfigValues(cmd.Flags(), profile)
+}
+
+// applyConfigValues sets the config flags that are not changed from the environment or the given profile.
+func applyConfigValues(flags *pflag.FlagSet, profile map[string]interface{}) (map[string]string, error) {
+	sources := map[string]string{}
+	var errs []error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if !configFlags[flag.Name] {
+			return
+		}
+
+		if flag.Changed {
+			sources[flag.Name] = sourceFlag
+			return
+		}
+
+		value, source, ok := lookupConfigValue(flag.Name, profile)
+		if !ok {
+			sources[flag.Name] = sourceDefault
+			return
+		}
+
+		// setting the value directly keeps the flag marked as not changed
+		if err := flag.Value.Set(value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid value %q for %s from %s: %w", value, flag.Name, source, err))
+			return
+		}
+
+		sources[flag.Name] = source
+	})
+
+	return sources, errors.Join(errs...)
+}
+
+func lookupConfigValue(flagName string, profile map[string]interface{}) (value, source string, ok bool) {
+	if env, ok := os.LookupEnv(envVar(flagName)); ok {
+		return env, sourceEnv, true
+	}
+
+	profileValue, ok := profile[flagName]
+	if !ok {
+		return "", "", false
+	}
+
+	if list, ok := profileValue.([]interface{}); ok {
+		items := make([]string, 0, len(list))
+		for _, item := range list {
+			items = append(items, fmt.Sprint(item))
+		}
+
+		return strings.Join(items, ","), sourceProfile, true
+	}
+
+	if object, ok := profileValue.(map[string]interface{}); ok {
+		items := make([]string, 0, len(object))
+		for key, item := range object {
+			items = append(items, fmt.Sprintf("%s=%v", key, item))
+		}
+		// the order of the map is random, keep the value stable for config view
+		sort.Strings(items)
+
+		return strings.Join(items, ","), sourceProfile, true
+	}
+
+	return fmt.Sprint(profileValue), sourceProfile, true
+}
+
+// envVar returns the name of the environment variable for the given flag,
+// e.g. KINDACOOL_PUBLIC_NETWORK_ID for publicNetworkID.
+func envVar(flagName string) string {
+	var (
+		name  strings.Builder
+		runes = []rune(flagName)
+	)
+	for i, r := range runes {
+		if r == '-' {
+			name.WriteRune('_')
+			continue
+		}
+
+		if i > 0 && unicode.IsUpper(r) {
+			previousLower := unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if previousLower || (nextLower && unicode.IsUpper(runes[i-1])) {
+				name.WriteRune('_')
+			}
+		}
+
+		name.WriteRune(unicode.ToUpper(r))
+	}
+
+	return envPrefix + name.String()
+}
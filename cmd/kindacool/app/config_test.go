@@ -0,0 +1,185 @@
+package app
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func TestEnvVar(t *testing.T) {
+	tests := []struct {
+		flagName string
+		want     string
+	}{
+		{"flavor", "KINDACOOL_FLAVOR"},
+		{"machineImage", "KINDACOOL_MACHINE_IMAGE"},
+		{"publicNetworkID", "KINDACOOL_PUBLIC_NETWORK_ID"},
+		{"publicIPPool", "KINDACOOL_PUBLIC_IP_POOL"},
+		{"additionalPorts", "KINDACOOL_ADDITIONAL_PORTS"},
+		{"log-format", "KINDACOOL_LOG_FORMAT"},
+		{"application-credential-secret", "KINDACOOL_APPLICATION_CREDENTIAL_SECRET"},
+		{"sshDialTimeout", "KINDACOOL_SSH_DIAL_TIMEOUT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.flagName, func(t *testing.T) {
+			if got := envVar(tt.flagName); got != tt.want {
+				t.Errorf("envVar(%q) = %q, want %q", tt.flagName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupConfigValue(t *testing.T) {
+	profile := map[string]interface{}{
+		"flavor":          "m1.large",
+		"nodeCount":       3,
+		"public":          true,
+		"additionalPorts": []interface{}{8080, 9090},
+		"plugin-version":  map[string]interface{}{"openstack": "v3.15.0", "command": "v1.0.0"},
+	}
+
+	tests := []struct {
+		name       string
+		flagName   string
+		env        map[string]string
+		wantValue  string
+		wantSource string
+		wantOK     bool
+	}{
+		{name: "string", flagName: "flavor", wantValue: "m1.large", wantSource: sourceProfile, wantOK: true},
+		{name: "number", flagName: "nodeCount", wantValue: "3", wantSource: sourceProfile, wantOK: true},
+		{name: "bool", flagName: "public", wantValue: "true", wantSource: sourceProfile, wantOK: true},
+		{name: "list", flagName: "additionalPorts", wantValue: "8080,9090", wantSource: sourceProfile, wantOK: true},
+		{
+			name: "map", flagName: "plugin-version",
+			wantValue: "command=v1.0.0,openstack=v3.15.0", wantSource: sourceProfile, wantOK: true,
+		},
+		{
+			name: "env takes precedence", flagName: "flavor", env: map[string]string{"KINDACOOL_FLAVOR": "m1.small"},
+			wantValue: "m1.small", wantSource: sourceEnv, wantOK: true,
+		},
+		{
+			name: "env only", flagName: "publicNetworkID", env: map[string]string{"KINDACOOL_PUBLIC_NETWORK_ID": "abc"},
+			wantValue: "abc", wantSource: sourceEnv, wantOK: true,
+		},
+		{name: "missing", flagName: "machineImage"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			value, source, ok := lookupConfigValue(tt.flagName, profile)
+			if value != tt.wantValue || source != tt.wantSource || ok != tt.wantOK {
+				t.Errorf(
+					"lookupConfigValue(%q) = (%q, %q, %v), want (%q, %q, %v)",
+					tt.flagName, value, source, ok, tt.wantValue, tt.wantSource, tt.wantOK,
+				)
+			}
+		})
+	}
+}
+
+func TestApplyConfigValues(t *testing.T) {
+	t.Setenv("KINDACOOL_ALL", "true")
+	t.Setenv("KINDACOOL_YES", "true")
+	t.Setenv("KINDACOOL_MACHINE_IMAGE", "Ubuntu 24.04")
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	all := flags.Bool("all", false, "")
+	yes := flags.Bool("yes", false, "")
+	dryRun := flags.Bool("dry-run", false, "")
+	flavor := flags.String("flavor", "m4.large", "")
+	image := flags.String("machineImage", "Ubuntu 22.04", "")
+	ports := flags.IntSlice("additionalPorts", nil, "")
+	nodeCount := flags.Int("nodeCount", 1, "")
+
+	if err := flags.Parse([]string{"--nodeCount", "2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	profile := map[string]interface{}{
+		"dry-run":         true,
+		"flavor":          "m1.large",
+		"nodeCount":       5,
+		"additionalPorts": []interface{}{8080},
+	}
+
+	sources, err := applyConfigValues(flags, profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *all || *yes || *dryRun {
+		t.Errorf("selection and confirmation flags were set from the config: all=%v yes=%v dry-run=%v", *all, *yes, *dryRun)
+	}
+
+	if *flavor != "m1.large" || *image != "Ubuntu 24.04" || *nodeCount != 2 || !reflect.DeepEqual(*ports, []int{8080}) {
+		t.Errorf("unexpected values: flavor=%q machineImage=%q nodeCount=%d additionalPorts=%v", *flavor, *image, *nodeCount, *ports)
+	}
+
+	wantSources := map[string]string{
+		"flavor":          sourceProfile,
+		"machineImage":    sourceEnv,
+		"nodeCount":       sourceFlag,
+		"additionalPorts": sourceProfile,
+	}
+	if !reflect.DeepEqual(sources, wantSources) {
+		t.Errorf("sources = %v, want %v", sources, wantSources)
+	}
+}
+
+func TestApplyConfigValuesInvalid(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.Int("nodeCount", 1, "")
+
+	if _, err := applyConfigValues(flags, map[string]interface{}{"nodeCount": "many"}); err == nil {
+		t.Error("expected an error for an invalid profile value")
+	}
+}
+
+func TestProfileUnknownKeys(t *testing.T) {
+	config := &UserConfig{Profiles: map[string]map[string]interface{}{
+		"valid":   {"flavor": "m1.large", "cloud": "prod", "plugin-version": map[string]interface{}{"openstack": "v3.15.0"}},
+		"unknown": {"flavor": "m1.large", "flavour": "m1.small", "yes": true},
+	}}
+
+	tests := []struct {
+		profile string
+		wantErr error
+	}{
+		{profile: "valid"},
+		{profile: "unknown", wantErr: ErrUnknownProfileKey},
+		{profile: "missing", wantErr: ErrUnknownProfile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().String(profileFlag, "", "")
+			if err := cmd.Flags().Parse([]string{"--" + profileFlag, tt.profile}); err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, err := config.Profile(cmd)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Profile() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigViewFlags(t *testing.T) {
+	flags := configViewFlags()
+	for name := range configFlags {
+		if flags.Lookup(name) == nil {
+			t.Errorf("config flag %q is missing in config view", name)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// ConfigView is the effective configuration as shown by the config view command.
+type ConfigView struct {
+	ConfigFile string                     `json:"configFile"`
+	Profile    string                     `json:"profile,omitempty"`
+	Values     map[string]ConfigViewValue `json:"values"`
+}
+
+type ConfigViewValue struct {
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+func BuildConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the user configuration",
+		Long: fmt.Sprintf(`The config command helps to inspect the user configuration.
+
+Defaults for the cluster settings can be set in profiles in the config file (~/.config/%[1]s/config.yaml):
+
+	currentProfile: prod-cloud
+	profiles:
+	  prod-cloud:
+	    flavor: m1.large
+	    machineImage: Ubuntu 24.04
+	    privateNetworkName: internal
+
+The profile is selected with --profile, $KINDACOOL_PROFILE or currentProfile.
+The cluster settings as well as the cloud, pulumi, plugin and log format flags
+can also be set with an environment variable like $KINDACOOL_MACHINE_IMAGE.
+Flags that select clusters or skip confirmations like --all, --yes or --dry-run are only taken from the command line.
+Explicitly set flags take precedence over environment variables, which take precedence over the profile.`, CLI),
+	}
+
+	cmd.AddCommand(BuildConfigViewCommand())
+
+	return cmd
+}
+
+func BuildConfigViewCommand() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "view",
+		Short: "Show the configuration in effect",
+		Long: `The view command shows the values that are used for the flags of 'cluster create' and where they come from.
+This includes the cloud, pulumi, plugin and log format flags that are inherited from the parent commands.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configFile, err := ConfigFile()
+			if err != nil {
+				return err
+			}
+
+			config, err := LoadUserConfig()
+			if err != nil {
+				return err
+			}
+
+			profileName, profile, err := config.Profile(cmd)
+			if err != nil {
+				return err
+			}
+
+			flags := configViewFlags()
+			sources, err := applyConfigValues(flags, profile)
+			if err != nil {
+				return err
+			}
+
+			view := ConfigView{
+				ConfigFile: configFile,
+				Profile:    profileName,
+				Values:     map[string]ConfigViewValue{},
+			}
+			flags.VisitAll(func(flag *pflag.Flag) {
+				if source, ok := sources[flag.Name]; ok {
+					view.Values[flag.Name] = ConfigViewValue{Value: flag.Value.String(), Source: source}
+				}
+			})
+
+			return printOutput(cmd.OutOrStdout(), output, view, func(w io.Writer) {
+				fmt.Fprintf(w, "Config File:\t%s\n", view.ConfigFile)
+				fmt.Fprintf(w, "Profile:\t%s\n\n", view.Profile)
+				fmt.Fprintln(w, "FLAG\tVALUE\tSOURCE")
+
+				flags := make([]string, 0, len(view.Values))
+				for flag := range view.Values {
+					flags = append(flags, flag)
+				}
+				sort.Strings(flags)
+
+				for _, flag := range flags {
+					fmt.Fprintf(w, "%s\t%s\t%s\n", flag, view.Values[flag].Value, view.Values[flag].Source)
+				}
+			})
+		},
+	}
+
+	addOutputFlag(cmd, &output, outputTable, outputJSON, outputYAML)
+
+	return cmd
+}
+
+// configViewFlags returns all flags of the create command, including the ones inherited from the root and cluster commands.
+func configViewFlags() *pflag.FlagSet {
+	root := BuildRootCommand()
+	createCmd, _, err := root.Find([]string{"cluster", "create"})
+	if err != nil {
+		// the command tree is static, so this can't happen
+		panic(err)
+	}
+
+	// merges the persistent flags of the parents into the command's flags
+	_ = createCmd.InheritedFlags()
+
+	return createCmd.Flags()
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/brumhard/kindacool/pkg/kindacool"
+
+	"github.com/spf13/cobra"
+)
+
+func BuildDeleteCommand() *cobra.Command {
+	var (
+		manager     = &kindacool.Manager{}
+		files       []string
+		yes         bool
+		parallelism int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Destroy clusters defined in spec files",
+		Long: fmt.Sprintf(`The delete command destroys all clusters that are defined in the given spec files.
+
+See '%s apply --help' for the format of the spec files.
+The clusters are destroyed in parallel and a summary is shown at the end.`, CLI),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return setupManager(cmd, manager)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			specs, err := readSpecs(cmd, files)
+			if err != nil {
+				return err
+			}
+
+			clusters := make([]string, 0, len(specs))
+			for _, spec := range specs {
+				clusters = append(clusters, spec.Metadata.Name)
+			}
+
+			if len(clusters) == 1 {
+				manager.Options.Name = clusters[0]
+				return destroy(cmd, manager)
+			}
+
+			return destroyClusters(cmd, manager, clusters, yes, parallelism)
+		},
+	}
+
+	addSpecFileFlag(cmd, &files)
+	cmd.Flags().BoolVarP(&manager.Options.Verbose, "verbose", "v", false, "Enable verbose pulumi output.")
+	addCloudFlags(cmd.Flags(), &manager.Options.Cloud)
+	addPulumiFlags(cmd.Flags(), &manager.Options.Pulumi)
+	addPluginFlags(cmd.Flags(), &manager.Options.Plugins)
+	_ = cmd.RegisterFlagCompletionFunc("cloud", completeCloudNames)
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Skip the confirmation when destroying multiple clusters.")
+	cmd.Flags().IntVar(
+		&parallelism,
+		"parallel", defaultDestroyParallelism,
+		"Maximum amount of clusters that are destroyed at the same time.",
+	)
+
+	return cmd
+}
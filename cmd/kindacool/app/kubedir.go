@@ -32,3 +32,13 @@ func KubeconfigFile(clusterName string) (string, error) {
 
 	return path.Join(kubeDir, fmt.Sprintf("kindacool-%s.yaml", clusterName)), nil
 }
+
+// TunnelKubeconfigFile returns the path of the kubeconfig that points to the local end of a cluster's tunnel.
+func TunnelKubeconfigFile(clusterName string) (string, error) {
+	kubeDir, err := KubeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(kubeDir, fmt.Sprintf("kindacool-%s-tunnel.yaml", clusterName)), nil
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+var (
+	ErrInvalidKubeconfig = errors.New("kubeconfig doesn't contain a current context")
+	ErrContextExists     = errors.New("context already exists for another cluster")
+)
+
+// KubeconfigMergeOptions configures how a cluster's kubeconfig is merged into the default kubeconfig.
+type KubeconfigMergeOptions struct {
+	Merge         bool
+	Context       string
+	SwitchContext bool
+}
+
+func addKubeconfigMergeFlags(cmd *cobra.Command, opts *KubeconfigMergeOptions, mergeFlag string) {
+	cmd.Flags().BoolVar(
+		&opts.Merge,
+		mergeFlag, false,
+		"Merge the cluster's kubeconfig into the default kubeconfig ($KUBECONFIG or ~/.kube/config).",
+	)
+	cmd.Flags().StringVar(
+		&opts.Context,
+		"context", "",
+		fmt.Sprintf("Name of the merged context. Defaults to %s-<clustername>.", CLI),
+	)
+	cmd.Flags().BoolVar(&opts.SwitchContext, "switch-context", false, "Use the merged context as current context.")
+}
+
+// managedEntryName returns the name of the cluster and user entries that are merged for a cluster.
+// It is used to find the entries again when the cluster is destroyed.
+func managedEntryName(clusterName string) string {
+	return fmt.Sprintf("%s-%s", CLI, clusterName)
+}
+
+// MergeKubeconfig merges the cluster, user and context of the given kubeconfig into the default kubeconfig.
+// Existing entries of the same cluster are replaced. It returns the name of the merged context.
+func MergeKubeconfig(kubeconfig []byte, clusterName string, opts KubeconfigMergeOptions) (string, error) {
+	source, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+
+	sourceContext, ok := source.Contexts[source.CurrentContext]
+	if !ok {
+		return "", ErrInvalidKubeconfig
+	}
+
+	cluster, clusterOK := source.Clusters[sourceContext.Cluster]
+	user, userOK := source.AuthInfos[sourceContext.AuthInfo]
+	if !clusterOK || !userOK {
+		return "", ErrInvalidKubeconfig
+	}
+
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	config, err := pathOptions.GetStartingConfig()
+	if err != nil {
+		return "", err
+	}
+
+	name := managedEntryName(clusterName)
+	contextName := opts.Context
+	if contextName == "" {
+		contextName = name
+	}
+
+	if existing, ok := config.Contexts[contextName]; ok && (existing.Cluster != name || existing.AuthInfo != name) {
+		return "", fmt.Errorf("%w: %q", ErrContextExists, contextName)
+	}
+
+	config.Clusters[name] = cluster
+	config.AuthInfos[name] = user
+	config.Contexts[contextName] = &clientcmdapi.Context{
+		Cluster:   name,
+		AuthInfo:  name,
+		Namespace: sourceContext.Namespace,
+	}
+
+	if opts.SwitchContext {
+		config.CurrentContext = contextName
+	}
+
+	if err := clientcmd.ModifyConfig(pathOptions, *config, false); err != nil {
+		return "", err
+	}
+
+	return contextName, nil
+}
+
+// RemoveMergedKubeconfig removes the entries that were added by MergeKubeconfig from the default kubeconfig.
+// All other entries are left untouched.
+func RemoveMergedKubeconfig(clusterName string) error {
+	pathOptions := clientcmd.NewDefaultPathOptions()
+	config, err := pathOptions.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	name := managedEntryName(clusterName)
+	changed := false
+	for contextName, context := range config.Contexts {
+		if context.Cluster != name || context.AuthInfo != name {
+			continue
+		}
+
+		delete(config.Contexts, contextName)
+		if config.CurrentContext == contextName {
+			config.CurrentContext = ""
+		}
+		changed = true
+	}
+
+	if _, ok := config.Clusters[name]; ok {
+		delete(config.Clusters, name)
+		changed = true
+	}
+
+	if _, ok := config.AuthInfos[name]; ok {
+		delete(config.AuthInfos, name)
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return clientcmd.ModifyConfig(pathOptions, *config, false)
+}
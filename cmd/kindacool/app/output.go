@@ -0,0 +1,57 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+var ErrUnknownOutputFormat = errors.New("unknown output format")
+
+// addOutputFlag adds the --output flag to the given command.
+// The first of the given formats is used as the default.
+func addOutputFlag(cmd *cobra.Command, output *string, formats ...string) {
+	cmd.Flags().StringVarP(
+		output,
+		"output", "o", formats[0],
+		fmt.Sprintf("Output format. One of %q.", formats),
+	)
+}
+
+// printOutput writes v to w in the requested format.
+// For the table format the given function is used to write the rows
+// which are aligned afterwards.
+func printOutput(w io.Writer, format string, v interface{}, table func(tw io.Writer)) error {
+	switch format {
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case outputYAML:
+		out, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(out)
+		return err
+	case outputTable:
+		//nolint:gomnd // padding between columns
+		tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		table(tw)
+		return tw.Flush()
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownOutputFormat, format)
+	}
+}
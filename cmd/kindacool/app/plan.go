@@ -0,0 +1,55 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/brumhard/kindacool/pkg/kindacool"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+const dryRunFlagUsage = `Only show the changes that would be executed without applying them.
+Exits with a non-zero code if there are pending changes.`
+
+const skipPreflightFlagUsage = `Skip the checks that the flavor, image and networks exist in OpenStack
+and that the project's quotas are sufficient before any resource is created.`
+
+// printPlan writes a human readable summary of the plan to w
+// and returns kindacool.ErrChangesPending if the plan contains any changes.
+func printPlan(w io.Writer, plan *kindacool.Plan) error {
+	if !plan.HasChanges() {
+		fmt.Fprintln(w, "No changes. Your cluster is up to date.")
+		return nil
+	}
+
+	symbols := map[apitype.OpType]string{
+		apitype.OpCreate:  "+",
+		apitype.OpUpdate:  "~",
+		apitype.OpReplace: "+-",
+		apitype.OpDelete:  "-",
+	}
+
+	//nolint:gomnd // padding between columns
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, change := range plan.Changes {
+		line := fmt.Sprintf("%s\t%s\t%s\t%s", symbols[change.Op], change.Op, change.Type, change.Name)
+		if len(change.ReplaceReasons) > 0 {
+			line += fmt.Sprintf("\t(replaced because of %s)", strings.Join(change.ReplaceReasons, ", "))
+		}
+		fmt.Fprintln(tw, line)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(
+		w, "\n%d to create, %d to update, %d to replace, %d to delete\n",
+		plan.Count(apitype.OpCreate), plan.Count(apitype.OpUpdate),
+		plan.Count(apitype.OpReplace), plan.Count(apitype.OpDelete),
+	)
+
+	return kindacool.ErrChangesPending
+}
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/brumhard/kindacool/pkg/k3s"
+	"github.com/brumhard/kindacool/pkg/kindacool"
+
+	"github.com/spf13/cobra"
+)
+
+const logFormatFlag = "log-format"
+
+// Exit codes for the different classes of errors.
+// They are part of the CLI's interface and must not be changed.
+const (
+	ExitOK             = 0
+	ExitError          = 1
+	ExitInvalidConfig  = 2
+	ExitEnvironment    = 3
+	ExitNotFound       = 4
+	ExitLocked         = 5
+	ExitDeployment     = 6
+	ExitChangesPending = 7
+	ExitAborted        = 8
+	ExitRemoteCommand  = 9
+	ExitPreflight      = 10
+)
+
+// Error categories in the result document, matching the exit codes.
+const (
+	categoryUnknown        = "unknown"
+	categoryInvalidConfig  = "invalid-config"
+	categoryEnvironment    = "environment"
+	categoryNotFound       = "not-found"
+	categoryLocked         = "locked"
+	categoryDeployment     = "deployment"
+	categoryChangesPending = "changes-pending"
+	categoryAborted        = "aborted"
+	categoryRemoteCommand  = "remote-command"
+	categoryPreflight      = "preflight"
+)
+
+var ErrInvalidUsage = errors.New("invalid usage")
+
+// Result is the document that is written to stdout after a command finished in JSON log format.
+type Result struct {
+	Command  string          `json:"command"`
+	Status   string          `json:"status"`
+	Clusters []ClusterResult `json:"clusters,omitempty"`
+	Error    *ResultError    `json:"error,omitempty"`
+	ExitCode int             `json:"exitCode"`
+}
+
+// ClusterResult is the outcome of a command for a single cluster.
+type ClusterResult struct {
+	Name           string     `json:"name"`
+	Status         string     `json:"status"`
+	KubeconfigPath string     `json:"kubeconfigPath,omitempty"`
+	Nodes          []k3s.Node `json:"nodes,omitempty"`
+	Error          string     `json:"error,omitempty"`
+}
+
+type ResultError struct {
+	Message  string `json:"message"`
+	Category string `json:"category"`
+	// FailedResources lists the resources that failed if a pulumi operation failed.
+	FailedResources []kindacool.ResourceFailure `json:"failedResources,omitempty"`
+}
+
+type resultKey struct{}
+
+// Execute runs the root command and returns the exit code for its outcome.
+// In JSON log format the result document is written to stdout, otherwise errors are written to stderr.
+func Execute(ctx context.Context) int {
+	result := &Result{}
+	root := BuildRootCommand()
+	root.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return fmt.Errorf("%w: %w", ErrInvalidUsage, err)
+	})
+
+	cmd, err := root.ExecuteContextC(context.WithValue(ctx, resultKey{}, result))
+	category, exitCode := classifyError(err)
+
+	if !jsonOutput(root) {
+		if err != nil {
+			fmt.Fprintf(root.ErrOrStderr(), "An error occurred: %s\n", err)
+		}
+
+		return exitCode
+	}
+
+	if err == nil && len(result.Clusters) == 0 {
+		// the command only printed data, e.g. ls
+		return exitCode
+	}
+
+	result.Command = cmd.CommandPath()
+	result.Status = kindacool.StatusSucceeded
+	result.ExitCode = exitCode
+	if err != nil {
+		result.Status = kindacool.StatusFailed
+		result.Error = &ResultError{Message: err.Error(), Category: category}
+
+		var deploymentErr *kindacool.DeploymentError
+		if errors.As(err, &deploymentErr) {
+			result.Error.FailedResources = deploymentErr.Failures
+		}
+	}
+
+	if err := printOutput(root.OutOrStdout(), outputJSON, result, nil); err != nil {
+		fmt.Fprintf(root.ErrOrStderr(), "An error occurred: %s\n", err)
+		return ExitError
+	}
+
+	return exitCode
+}
+
+// classifyError returns the category and exit code for the given error.
+func classifyError(err error) (string, int) {
+	classes := []struct {
+		category string
+		exitCode int
+		errs     []error
+	}{
+		{categoryInvalidConfig, ExitInvalidConfig, []error{
+			kindacool.ErrInvalidConfig, kindacool.ErrInvalidBastion,
+			ErrInvalidUsage, ErrUnknownProfile, ErrNoSpecFiles, ErrUnknownOutputFormat,
+		}},
+		{categoryEnvironment, ExitEnvironment, []error{
+			kindacool.ErrPulumiNotInPath, kindacool.ErrUnauthorized, kindacool.ErrNoBackend, kindacool.ErrCloudMismatch,
+			kindacool.ErrInvalidCACert, kindacool.ErrPulumiInstall, kindacool.ErrChecksumMissing, kindacool.ErrChecksumInvalid,
+			kindacool.ErrPluginInstall, kindacool.ErrPluginVersion,
+		}},
+		{categoryNotFound, ExitNotFound, []error{
+			kindacool.ErrOutputUnavailable, kindacool.ErrNodeNotFound, kindacool.ErrNoNodes,
+		}},
+		{categoryLocked, ExitLocked, []error{kindacool.ErrStackLocked}},
+		{categoryDeployment, ExitDeployment, []error{
+			kindacool.ErrRefreshFailed, kindacool.ErrUpdateFailed, kindacool.ErrDestroyFailed, ErrDestroyFailed, kindacool.ErrNotReady,
+		}},
+		{categoryChangesPending, ExitChangesPending, []error{kindacool.ErrChangesPending}},
+		{categoryAborted, ExitAborted, []error{ErrAborted, context.Canceled}},
+		{categoryRemoteCommand, ExitRemoteCommand, []error{ErrRemoteCommandFailed, ErrCopyFailed}},
+		{categoryPreflight, ExitPreflight, []error{kindacool.ErrPreflightFailed}},
+	}
+
+	if err == nil {
+		return "", ExitOK
+	}
+
+	for _, class := range classes {
+		for _, target := range class.errs {
+			if errors.Is(err, target) {
+				return class.category, class.exitCode
+			}
+		}
+	}
+
+	return categoryUnknown, ExitError
+}
+
+// jsonOutput reports whether the command logs in JSON format.
+func jsonOutput(cmd *cobra.Command) bool {
+	flag := cmd.Flag(logFormatFlag)
+	return flag != nil && flag.Value.String() == string(kindacool.LogFormatJSON)
+}
+
+// recordCluster adds the outcome for a cluster to the result document.
+func recordCluster(cmd *cobra.Command, clusterResult ClusterResult) {
+	result, ok := cmd.Context().Value(resultKey{}).(*Result)
+	if !ok {
+		return
+	}
+
+	result.Clusters = append(result.Clusters, clusterResult)
+}
@@ -3,6 +3,8 @@ package app
 import (
 	"fmt"
 
+	"github.com/brumhard/kindacool/pkg/kindacool"
+
 	"github.com/spf13/cobra"
 )
 
@@ -14,7 +16,8 @@ func BuildRootCommand() *cobra.Command {
 		Short: fmt.Sprintf("%s can be used to quickly setup new Kubernetes (k3s) clusters on OpenStack.", CLI),
 		Long: fmt.Sprintf(`%[1]s is a CLI to quickly setup new Kubernetes (k3s) clusters on OpenStack.
 It uses Pulumi Automation API in the background.
-Before usage it is required to install pulumi and login to a backend. Also you need to source an .openrc file.
+Before usage it is required to install pulumi and login to a backend.
+Also you need OpenStack credentials from an .openrc file, a clouds.yaml entry or an application credential.
 
 A cluster can then be setup with:
 	$ %[1]s cluster create
@@ -27,10 +30,29 @@ For more information, please visit the project's homepage: https://github.com/br
 		// don't show errors and usage on errors in any RunE function.
 		SilenceErrors: true,
 		SilenceUsage:  true,
+		// the completion command is added explicitly to document the dynamic completions.
+		CompletionOptions: cobra.CompletionOptions{DisableDefaultCmd: true},
 	}
 
+	cmd.PersistentFlags().String(
+		profileFlag, "",
+		fmt.Sprintf("Profile from the config file to use for the settings that are not set explicitly. Use '%s config view' to show the values in effect.", CLI),
+	)
+
+	cmd.PersistentFlags().String(
+		logFormatFlag, string(kindacool.LogFormatText),
+		`Format of the log output. One of "text" or "json".
+With json every stage and resource event is written as a JSON line to stderr
+and a result document including the error category is written to stdout.`,
+	)
+
 	cmd.AddCommand(BuildVersionCommand())
 	cmd.AddCommand(BuildClusterCommand())
+	cmd.AddCommand(BuildApplyCommand())
+	cmd.AddCommand(BuildDeleteCommand())
+	cmd.AddCommand(BuildConfigCommand())
+	cmd.AddCommand(BuildAuthCommand())
+	cmd.AddCommand(BuildCompletionCommand())
 
 	return cmd
 }
@@ -2,22 +2,132 @@ package app
 
 import (
 	"fmt"
+	"io"
+	"runtime"
 	"runtime/debug"
+	"strings"
+
+	"github.com/brumhard/kindacool/pkg/kindacool"
 
 	"github.com/spf13/cobra"
 )
 
+// these are set with -ldflags "-X github.com/brumhard/kindacool/cmd/kindacool/app.version=..." on release builds.
+// If they are not set, the values of the module's build info are used.
+//
+//nolint:gochecknoglobals // can only be set by ldflags on variables
+var (
+	version = ""
+	commit  = ""
+	date    = ""
+)
+
+// VersionInfo describes the build of the CLI and the tools it depends on.
+type VersionInfo struct {
+	Version       string             `json:"version"`
+	Commit        string             `json:"commit,omitempty"`
+	Date          string             `json:"date,omitempty"`
+	GoVersion     string             `json:"goVersion"`
+	Platform      string             `json:"platform"`
+	PulumiVersion string             `json:"pulumiVersion,omitempty"`
+	Plugins       []kindacool.Plugin `json:"plugins"`
+	Warnings      []string           `json:"warnings,omitempty"`
+}
+
 func BuildVersionCommand() *cobra.Command {
+	var output string
+
 	cmd := &cobra.Command{
 		Use:   "version",
 		Short: fmt.Sprintf("Print the version number of %s", CLI),
-		Long:  fmt.Sprintf("All software has versions. This is %s's.", CLI),
-		Run: func(cmd *cobra.Command, args []string) {
-			if bi, ok := debug.ReadBuildInfo(); ok {
-				fmt.Printf("%+v\n", bi)
+		Long: fmt.Sprintf(`All software has versions. This is %s's.
+
+Besides the version of the CLI itself, the pinned pulumi plugins and the version of the pulumi CLI in $PATH are shown.
+Without a pulumi CLI in $PATH the version of the managed pulumi CLI is shown.
+A warning is printed if the pulumi CLI's version is not supported.`, CLI),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info := buildVersionInfo()
+
+			if output == outputTable {
+				for _, warning := range info.Warnings {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %s\n", warning)
+				}
 			}
+
+			return printOutput(cmd.OutOrStdout(), output, info, func(w io.Writer) {
+				fmt.Fprintf(w, "%s:\t%s\n", CLI, info.Version)
+				fmt.Fprintf(w, "commit:\t%s\n", valueOrUnknown(info.Commit))
+				fmt.Fprintf(w, "built:\t%s\n", valueOrUnknown(info.Date))
+				fmt.Fprintf(w, "go:\t%s %s\n", info.GoVersion, info.Platform)
+				fmt.Fprintf(w, "pulumi:\t%s\n", valueOrUnknown(info.PulumiVersion))
+
+				plugins := make([]string, 0, len(info.Plugins))
+				for _, plugin := range info.Plugins {
+					plugins = append(plugins, fmt.Sprintf("%s %s", plugin.Name, valueOrUnknown(plugin.Version)))
+				}
+				fmt.Fprintf(w, "plugins:\t%s\n", strings.Join(plugins, ", "))
+			})
 		},
 	}
 
+	addOutputFlag(cmd, &output, outputTable, outputJSON, outputYAML)
+
 	return cmd
 }
+
+func buildVersionInfo() VersionInfo {
+	info := VersionInfo{
+		Version:   version,
+		Commit:    commit,
+		Date:      date,
+		GoVersion: runtime.Version(),
+		Platform:  fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH),
+	}
+
+	plugins, err := kindacool.RequiredPlugins()
+	info.Plugins = plugins
+	if err != nil {
+		info.Warnings = append(info.Warnings, err.Error())
+	}
+
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" {
+			info.Version = buildInfo.Main.Version
+		}
+
+		for _, setting := range buildInfo.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.Date == "":
+				info.Date = setting.Value
+			}
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "(devel)"
+	}
+
+	pulumiVersion, err := kindacool.PulumiVersion()
+	if err != nil {
+		info.Warnings = append(info.Warnings, err.Error())
+		return info
+	}
+
+	info.PulumiVersion = pulumiVersion.String()
+	if err := kindacool.CheckPulumiVersion(pulumiVersion); err != nil {
+		info.Warnings = append(info.Warnings, err.Error())
+	}
+
+	return info
+}
+
+func valueOrUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+
+	return value
+}
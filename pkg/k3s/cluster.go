@@ -3,10 +3,12 @@ package k3s
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/pulumi/pulumi-command/sdk/go/command/remote"
+	"github.com/pulumi/pulumi-openstack/sdk/v3/go/openstack"
 	"github.com/pulumi/pulumi-openstack/sdk/v3/go/openstack/compute"
 	"github.com/pulumi/pulumi-openstack/sdk/v3/go/openstack/images"
 	"github.com/pulumi/pulumi-openstack/sdk/v3/go/openstack/networking"
@@ -15,37 +17,84 @@ import (
 
 const (
 	sshPort = 22
+	// KubeAPIServerPort is the port the Kubernetes API server of the server node listens on.
+	KubeAPIServerPort = 6443
+)
+
+const (
+	RoleServer = "server"
+	RoleWorker = "worker"
 )
 
-var ErrInvalidNodeCount = errors.New("invalid amount of nodes, must be > 0")
 var ErrImageNotFound = errors.New("image not found by name")
-var ErrPrivateNetwork = errors.New("privateNetworkName is empty (required if public is set to false)")
-var ErrPublicNetwork = errors.New("public network infos are missing")
 
 type Cluster struct {
 	pulumi.ResourceState
-	ClusterArgs ClusterArgs         `pulumi:"ClusterArgs"`
-	Kubeconfig  pulumi.StringOutput `pulumi:"Kubeconfig"`
-	SSHKey      pulumi.StringOutput `pulumi:"SSHKey"`
+	ClusterArgs        ClusterArgs            `pulumi:"ClusterArgs"`
+	Kubeconfig         pulumi.StringOutput    `pulumi:"Kubeconfig"`
+	SSHKey             pulumi.StringOutput    `pulumi:"SSHKey"`
+	K3sVersion         pulumi.StringOutput    `pulumi:"K3sVersion"`
+	Nodes              pulumi.MapArrayOutput  `pulumi:"Nodes"`
+	NetworkIDs         pulumi.StringMapOutput `pulumi:"NetworkIDs"`
+	SecurityGroupRules pulumi.MapArrayOutput  `pulumi:"SecurityGroupRules"`
 }
 
 type ClusterArgs struct {
-	AdditionalPorts    []int
-	MachineFlavor      string
-	NodeCount          int
-	VolumeSize         int
-	MachineImage       string
-	MachineUser        string
-	PrivateNetworkName string
-	Public             bool
-	PublicIPPool       string
-	PublicNetworkName  string
-	PublicNetworkID    string
+	AdditionalPorts    []int  `json:"additionalPorts,omitempty"`
+	MachineFlavor      string `json:"flavor"`
+	NodeCount          int    `json:"nodeCount"`
+	VolumeSize         int    `json:"volumeSize,omitempty"`
+	MachineImage       string `json:"machineImage"`
+	MachineUser        string `json:"machineUser"`
+	PrivateNetworkName string `json:"privateNetworkName,omitempty"`
+	Public             bool   `json:"public"`
+	PublicIPPool       string `json:"publicIPPool,omitempty"`
+	PublicNetworkName  string `json:"publicNetworkName,omitempty"`
+	PublicNetworkID    string `json:"publicNetworkID,omitempty"`
+	// SSHDialTimeout is the timeout in seconds of a single SSH connection attempt to install k3s.
+	// The default of the command provider is used if it's 0.
+	SSHDialTimeout int `json:"sshDialTimeout,omitempty"`
+	// SSHDialRetries is the number of failed SSH connection attempts before installing k3s fails.
+	// The default of the command provider is used if it's 0, -1 retries without a limit.
+	SSHDialRetries int `json:"sshDialRetries,omitempty"`
+}
+
+// Node contains the details of a single VM that is part of the cluster.
+// It is the decoded form of the entries in Cluster.Nodes.
+type Node struct {
+	Name       string `json:"name"`
+	Role       string `json:"role"`
+	PrivateIP  string `json:"privateIP"`
+	FloatingIP string `json:"floatingIP,omitempty"`
+}
+
+// SecurityGroupRule is the decoded form of the entries in Cluster.SecurityGroupRules.
+type SecurityGroupRule struct {
+	Port        int    `json:"port"`
+	Protocol    string `json:"protocol"`
+	Description string `json:"description"`
+}
+
+// Providers configures the providers of the cluster's resources.
+type Providers struct {
+	// OpenStack creates all OpenStack resources, the default provider is never used.
+	OpenStack *openstack.Provider
+	// CommandVersion is the version of the command plugin that installs k3s on the nodes.
+	// The version of its Go SDK is used if it's empty.
+	CommandVersion string
 }
 
 // NewCluster is the pulumi program to create a new k3s cluster on top of OpenStack.
-func NewCluster(ctx *pulumi.Context, name string, args *ClusterArgs, opts ...pulumi.ResourceOption) (*Cluster, error) {
+func NewCluster(
+	ctx *pulumi.Context, name string, args *ClusterArgs, providers Providers, opts ...pulumi.ResourceOption,
+) (*Cluster, error) {
+	if err := args.Validate(); err != nil {
+		return nil, err
+	}
+
+	provider := providers.OpenStack
 	cluster := &Cluster{}
+	opts = append(opts, pulumi.Providers(provider))
 	err := ctx.RegisterComponentResource("pkg:k3s:Cluster", name, cluster, opts...)
 	if err != nil {
 		return nil, err
@@ -58,37 +107,23 @@ func NewCluster(ctx *pulumi.Context, name string, args *ClusterArgs, opts ...pul
 		return nil, err
 	}
 
-	secGroupID, err := setupSecurityGroup(ctx, name, args.AdditionalPorts, opts...)
+	secGroupID, secGroupRules, err := setupSecurityGroup(ctx, name, args.AdditionalPorts, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	if !args.Public && args.PrivateNetworkName == "" {
-		return nil, ErrPrivateNetwork
-	}
-
-	if args.Public &&
-		(args.PublicIPPool == "" ||
-			args.PublicNetworkName == "" ||
-			args.PublicNetworkID == "") {
-		return nil, ErrPublicNetwork
-	}
-
 	networkName := pulumi.String(args.PrivateNetworkName).ToStringOutput()
+	networkIDs := pulumi.StringMap{}
 	if args.Public {
-		networkName, err = setupPublicNetworking(ctx, name, args, opts...)
+		networkName, networkIDs, err = setupPublicNetworking(ctx, name, args, opts...)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	if args.NodeCount <= 0 {
-		return nil, ErrInvalidNodeCount
-	}
-
 	image, err := images.LookupImage(ctx, &images.LookupImageArgs{
 		Name: pulumi.StringRef(args.MachineImage),
-	})
+	}, pulumi.Provider(provider))
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrImageNotFound, err)
 	}
@@ -112,6 +147,7 @@ func NewCluster(ctx *pulumi.Context, name string, args *ClusterArgs, opts ...pul
 	}
 
 	instanceAddresses := make([]pulumi.StringOutput, 0, args.NodeCount)
+	nodes := make(pulumi.MapArray, 0, args.NodeCount)
 	for i := 0; i < args.NodeCount; i++ {
 		resourceName := fmt.Sprintf("%s-node-%d", name, i)
 		instance, err := compute.NewInstance(ctx, resourceName, &compute.InstanceArgs{
@@ -129,14 +165,32 @@ func NewCluster(ctx *pulumi.Context, name string, args *ClusterArgs, opts ...pul
 		}
 
 		address := instance.AccessIpV4
+		floatingIP := pulumi.String("").ToStringOutput()
 		if args.Public {
 			address, err = setupFIP(ctx, resourceName, args.PublicIPPool, instance.ID(), opts...)
 			if err != nil {
 				return nil, err
 			}
+			floatingIP = address
+		}
+
+		if i == 0 && !args.Public {
+			// the private network already exists, so its id is only known after attaching the first instance
+			networkIDs["network"] = instance.Networks.Index(pulumi.Int(0)).Uuid().Elem()
+		}
+
+		role := RoleWorker
+		if i == 0 {
+			role = RoleServer
 		}
 
 		instanceAddresses = append(instanceAddresses, address)
+		nodes = append(nodes, pulumi.Map{
+			"name":       instance.Name,
+			"role":       pulumi.String(role),
+			"privateIP":  instance.AccessIpV4,
+			"floatingIP": floatingIP,
+		})
 	}
 
 	masterNodeAddress := instanceAddresses[0]
@@ -147,22 +201,38 @@ func NewCluster(ctx *pulumi.Context, name string, args *ClusterArgs, opts ...pul
 	}
 
 	connectionArgsFor := func(host pulumi.StringInput) *remote.ConnectionArgs {
-		return &remote.ConnectionArgs{
+		connectionArgs := &remote.ConnectionArgs{
 			Host:       host,
 			User:       pulumi.String(args.MachineUser),
 			PrivateKey: keyPair.PrivateKey,
 			Port:       pulumi.Float64(sshPort),
 		}
+
+		// unset values keep the inputs of existing clusters unchanged
+		if args.SSHDialTimeout > 0 {
+			connectionArgs.PerDialTimeout = pulumi.IntPtr(args.SSHDialTimeout)
+		}
+		if args.SSHDialRetries != 0 {
+			connectionArgs.DialErrorLimit = pulumi.IntPtr(args.SSHDialRetries)
+		}
+
+		return connectionArgs
 	}
 
-	kubeconfig, token, err := installK3sMaster(ctx, name, connectionArgsFor(masterNodeAddress), opts...)
+	// the SDK pins its own version on every resource, the option overrides it
+	commandOpts := append([]pulumi.ResourceOption{}, opts...)
+	if providers.CommandVersion != "" {
+		commandOpts = append(commandOpts, pulumi.Version(providers.CommandVersion))
+	}
+
+	kubeconfig, token, k3sVersion, err := installK3sMaster(ctx, name, connectionArgsFor(masterNodeAddress), commandOpts...)
 	if err != nil {
 		return nil, err
 	}
 
 	for i, nodeAddress := range workerNodeAddresses {
 		if err := installK3sWorker(
-			ctx, strconv.Itoa(i), masterNodeAddress, token, connectionArgsFor(nodeAddress), opts...,
+			ctx, strconv.Itoa(i), masterNodeAddress, token, connectionArgsFor(nodeAddress), commandOpts...,
 		); err != nil {
 			return nil, err
 		}
@@ -170,40 +240,64 @@ func NewCluster(ctx *pulumi.Context, name string, args *ClusterArgs, opts ...pul
 
 	cluster.Kubeconfig = kubeconfig
 	cluster.SSHKey = keyPair.PrivateKey
+	cluster.K3sVersion = k3sVersion
+	cluster.Nodes = nodes.ToMapArrayOutput()
+	cluster.NetworkIDs = networkIDs.ToStringMapOutput()
+	cluster.SecurityGroupRules = secGroupRules
 
 	ctx.RegisterResourceOutputs(cluster, pulumi.Map{
-		"Kubeconfig": cluster.Kubeconfig,
-		"SSHKey":     cluster.SSHKey,
+		"Kubeconfig":         cluster.Kubeconfig,
+		"SSHKey":             cluster.SSHKey,
+		"K3sVersion":         cluster.K3sVersion,
+		"Nodes":              cluster.Nodes,
+		"NetworkIDs":         cluster.NetworkIDs,
+		"SecurityGroupRules": cluster.SecurityGroupRules,
 	})
 
 	return cluster, nil
 }
 
+// SecurityGroupPorts returns the ports that are opened for the cluster with their description.
+func SecurityGroupPorts(additionalPorts []int) map[int]string {
+	ports := map[int]string{
+		sshPort:           "ssh",
+		KubeAPIServerPort: "kube-apiserver",
+		443:               "https",
+		80:                "http",
+	}
+
+	for _, port := range additionalPorts {
+		ports[port] = "additional-port"
+	}
+
+	return ports
+}
+
 func setupSecurityGroup(
 	ctx *pulumi.Context,
 	name string,
 	additionalPorts []int,
 	opts ...pulumi.ResourceOption,
-) (pulumi.StringOutput, error) {
+) (pulumi.StringOutput, pulumi.MapArrayOutput, error) {
 	secGroup, err := networking.NewSecGroup(ctx, name, &networking.SecGroupArgs{
 		Description: pulumi.Sprintf("sec group for kindacool cluster %s", name),
 	}, opts...)
 	if err != nil {
-		return pulumi.StringOutput{}, err
+		return pulumi.StringOutput{}, pulumi.MapArrayOutput{}, err
 	}
 
-	secGroupRules := map[int]string{
-		sshPort: "ssh",
-		6443:    "kube-apiserver",
-		443:     "https",
-		80:      "http",
-	}
+	secGroupRules := SecurityGroupPorts(additionalPorts)
 
-	for _, port := range additionalPorts {
-		secGroupRules[port] = "additional-port"
+	// the order of the map is random, keep the exported rules stable between updates
+	ports := make([]int, 0, len(secGroupRules))
+	for port := range secGroupRules {
+		ports = append(ports, port)
 	}
+	sort.Ints(ports)
 
-	for port, desc := range secGroupRules {
+	rules := make(pulumi.MapArray, 0, len(secGroupRules))
+	for _, port := range ports {
+		desc := secGroupRules[port]
 		_, err = networking.NewSecGroupRule(ctx, fmt.Sprintf("%s-%s-%d", name, desc, port), &networking.SecGroupRuleArgs{
 			Direction:       pulumi.String("ingress"),
 			SecurityGroupId: secGroup.ID().ToStringOutput(),
@@ -214,19 +308,27 @@ func setupSecurityGroup(
 			Protocol:        pulumi.StringPtr("tcp"),
 		}, opts...)
 		if err != nil {
-			return pulumi.StringOutput{}, err
+			return pulumi.StringOutput{}, pulumi.MapArrayOutput{}, err
 		}
+
+		rules = append(rules, pulumi.Map{
+			"port":        pulumi.Int(port),
+			"protocol":    pulumi.String("tcp"),
+			"description": pulumi.String(desc),
+		})
 	}
 
-	return secGroup.ID().ToStringOutput(), nil
+	return secGroup.ID().ToStringOutput(), rules.ToMapArrayOutput(), nil
 }
 
-func setupPublicNetworking(ctx *pulumi.Context, name string, args *ClusterArgs, opts ...pulumi.ResourceOption) (pulumi.StringOutput, error) {
+func setupPublicNetworking(
+	ctx *pulumi.Context, name string, args *ClusterArgs, opts ...pulumi.ResourceOption,
+) (pulumi.StringOutput, pulumi.StringMap, error) {
 	network, err := networking.NewNetwork(ctx, name, &networking.NetworkArgs{
 		AdminStateUp: pulumi.Bool(true),
 	}, opts...)
 	if err != nil {
-		return pulumi.StringOutput{}, err
+		return pulumi.StringOutput{}, nil, err
 	}
 
 	subnet, err := networking.NewSubnet(ctx, name, &networking.SubnetArgs{
@@ -235,14 +337,14 @@ func setupPublicNetworking(ctx *pulumi.Context, name string, args *ClusterArgs,
 		Cidr:        pulumi.String("10.0.0.0/16"),
 	}, opts...)
 	if err != nil {
-		return pulumi.StringOutput{}, err
+		return pulumi.StringOutput{}, nil, err
 	}
 
 	// TODO(brumhard): replace this with actual openstack client since that only requires the network name and not the id as well
 	// (at least it should since `openstack network show` does)
 	externalNet, err := networking.GetNetwork(ctx, args.PublicNetworkName, pulumi.ID(args.PublicNetworkID), nil, opts...)
 	if err != nil {
-		return pulumi.StringOutput{}, err
+		return pulumi.StringOutput{}, nil, err
 	}
 
 	router, err := networking.NewRouter(ctx, name, &networking.RouterArgs{
@@ -250,7 +352,7 @@ func setupPublicNetworking(ctx *pulumi.Context, name string, args *ClusterArgs,
 		ExternalNetworkId: externalNet.ID(),
 	}, opts...)
 	if err != nil {
-		return pulumi.StringOutput{}, err
+		return pulumi.StringOutput{}, nil, err
 	}
 
 	_, err = networking.NewRouterInterface(ctx, name, &networking.RouterInterfaceArgs{
@@ -258,10 +360,17 @@ func setupPublicNetworking(ctx *pulumi.Context, name string, args *ClusterArgs,
 		SubnetId: subnet.ID(),
 	}, opts...)
 	if err != nil {
-		return pulumi.StringOutput{}, err
+		return pulumi.StringOutput{}, nil, err
 	}
 
-	return network.Name, nil
+	networkIDs := pulumi.StringMap{
+		"network":         network.ID().ToStringOutput(),
+		"subnet":          subnet.ID().ToStringOutput(),
+		"router":          router.ID().ToStringOutput(),
+		"externalNetwork": externalNet.ID().ToStringOutput(),
+	}
+
+	return network.Name, networkIDs, nil
 }
 
 func setupFIP(
@@ -291,7 +400,7 @@ func installK3sMaster(
 	name string,
 	connectionArgs *remote.ConnectionArgs,
 	opts ...pulumi.ResourceOption,
-) (kubeconfig pulumi.StringOutput, token pulumi.StringOutput, err error) {
+) (kubeconfig, token, version pulumi.StringOutput, err error) {
 	installer, err := remote.NewCommand(ctx, "k3s", &remote.CommandArgs{
 		// TODO: make channel/version configurable
 		Create: pulumi.Sprintf(
@@ -304,7 +413,7 @@ func installK3sMaster(
 		Connection: connectionArgs,
 	}, opts...)
 	if err != nil {
-		return pulumi.StringOutput{}, pulumi.StringOutput{}, err
+		return pulumi.StringOutput{}, pulumi.StringOutput{}, pulumi.StringOutput{}, err
 	}
 
 	tokenRetriever, err := remote.NewCommand(ctx, "extract-token", &remote.CommandArgs{
@@ -312,7 +421,7 @@ func installK3sMaster(
 		Connection: connectionArgs,
 	}, append(opts, pulumi.DependsOn([]pulumi.Resource{installer}))...)
 	if err != nil {
-		return pulumi.StringOutput{}, pulumi.StringOutput{}, err
+		return pulumi.StringOutput{}, pulumi.StringOutput{}, pulumi.StringOutput{}, err
 	}
 
 	token = tokenRetriever.Stdout.ApplyT(func(tokenWithWhitespace string) string {
@@ -324,7 +433,7 @@ func installK3sMaster(
 		Connection: connectionArgs,
 	}, append(opts, pulumi.DependsOn([]pulumi.Resource{installer}))...)
 	if err != nil {
-		return pulumi.StringOutput{}, pulumi.StringOutput{}, err
+		return pulumi.StringOutput{}, pulumi.StringOutput{}, pulumi.StringOutput{}, err
 	}
 
 	kubeconfig = pulumi.All(kubeconfigRetriever.Stdout, connectionArgs.Host).ApplyT(func(args []interface{}) string {
@@ -337,7 +446,19 @@ func installK3sMaster(
 		return kubeconfigReplacer.Replace(args[0].(string))
 	}).(pulumi.StringOutput)
 
-	return kubeconfig, token, nil
+	versionRetriever, err := remote.NewCommand(ctx, "extract-version", &remote.CommandArgs{
+		Create:     pulumi.String("k3s --version | head -n 1"),
+		Connection: connectionArgs,
+	}, append(opts, pulumi.DependsOn([]pulumi.Resource{installer}))...)
+	if err != nil {
+		return pulumi.StringOutput{}, pulumi.StringOutput{}, pulumi.StringOutput{}, err
+	}
+
+	version = versionRetriever.Stdout.ApplyT(func(versionWithWhitespace string) string {
+		return strings.TrimSpace(versionWithWhitespace)
+	}).(pulumi.StringOutput)
+
+	return kubeconfig, token, version, nil
 }
 
 func installK3sWorker(
@@ -351,8 +472,9 @@ func installK3sWorker(
 	_, err := remote.NewCommand(ctx, fmt.Sprintf("k3s-worker-%s", name), &remote.CommandArgs{
 		Create: pulumi.Sprintf(
 			`curl -sfL https://get.k3s.io | \
-				K3S_URL=https://%s:6443 K3S_TOKEN=%s sh -`,
+				K3S_URL=https://%s:%d K3S_TOKEN=%s sh -`,
 			masterAddress,
+			KubeAPIServerPort,
 			masterToken,
 		),
 		Update:     pulumi.String("echo 'just chilling'"),
@@ -0,0 +1,110 @@
+package k3s
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+const maxPort = 65535
+
+// machineUserPattern matches the user names that are accepted by useradd on common distributions.
+const machineUserPattern = `^[a-z_][a-z0-9_-]*$`
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+// FieldError describes a problem with a single field of the configuration.
+type FieldError struct {
+	Field   string
+	Problem string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Problem)
+}
+
+func (e *FieldError) Unwrap() error {
+	return ErrInvalidConfig
+}
+
+// Validate checks the args before any resource is created.
+// All problems are returned at once as FieldErrors that are named like the JSON fields.
+func (a *ClusterArgs) Validate() error {
+	var errs []error
+	addProblem := func(field, problem string) {
+		errs = append(errs, &FieldError{Field: field, Problem: problem})
+	}
+
+	if a.MachineFlavor == "" {
+		addProblem("flavor", "is required")
+	}
+
+	if a.MachineImage == "" {
+		addProblem("machineImage", "is required")
+	}
+
+	if !regexp.MustCompile(machineUserPattern).MatchString(a.MachineUser) {
+		addProblem("machineUser", fmt.Sprintf("%q is not a valid user name", a.MachineUser))
+	}
+
+	if a.NodeCount <= 0 {
+		addProblem("nodeCount", fmt.Sprintf("must be at least 1, got %d", a.NodeCount))
+	}
+
+	if a.VolumeSize < 0 {
+		addProblem("volumeSize", fmt.Sprintf("must not be negative, got %d", a.VolumeSize))
+	}
+
+	if a.SSHDialTimeout < 0 {
+		addProblem("sshDialTimeout", fmt.Sprintf("must not be negative, got %d", a.SSHDialTimeout))
+	}
+
+	// the command provider retries without a limit for -1
+	if a.SSHDialRetries < -1 {
+		addProblem("sshDialRetries", fmt.Sprintf("must be -1 for unlimited retries or not negative, got %d", a.SSHDialRetries))
+	}
+
+	defaultPorts := SecurityGroupPorts(nil)
+	for _, port := range a.AdditionalPorts {
+		switch {
+		case port <= 0 || port > maxPort:
+			addProblem("additionalPorts", fmt.Sprintf("%d is not a valid port", port))
+		case defaultPorts[port] != "":
+			addProblem("additionalPorts", fmt.Sprintf("%d is always opened for %s", port, defaultPorts[port]))
+		}
+	}
+
+	for _, problem := range a.networkProblems() {
+		addProblem(problem.Field, problem.Problem)
+	}
+
+	return errors.Join(errs...)
+}
+
+// networkProblems checks that either the private network or all the public network settings are set.
+// The settings of the other mode are ignored, since profiles usually define the networks for both modes.
+func (a *ClusterArgs) networkProblems() []FieldError {
+	if !a.Public {
+		if a.PrivateNetworkName == "" {
+			return []FieldError{{"privateNetworkName", "is required if public is not set"}}
+		}
+
+		return nil
+	}
+
+	var problems []FieldError
+	for _, field := range []struct {
+		name  string
+		value string
+	}{
+		{"publicIPPool", a.PublicIPPool},
+		{"publicNetworkName", a.PublicNetworkName},
+		{"publicNetworkID", a.PublicNetworkID},
+	} {
+		if field.value == "" {
+			problems = append(problems, FieldError{field.name, "is required if public is set"})
+		}
+	}
+
+	return problems
+}
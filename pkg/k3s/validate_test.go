@@ -0,0 +1,164 @@
+package k3s
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestClusterArgsValidate(t *testing.T) {
+	validArgs := func(modify func(args *ClusterArgs)) *ClusterArgs {
+		args := &ClusterArgs{
+			MachineFlavor:      "m4.large",
+			MachineImage:       "Ubuntu 22.04",
+			MachineUser:        "ubuntu",
+			NodeCount:          1,
+			PrivateNetworkName: "internal",
+		}
+		if modify != nil {
+			modify(args)
+		}
+
+		return args
+	}
+
+	tests := []struct {
+		name string
+		args *ClusterArgs
+		want []string
+	}{
+		{name: "valid", args: validArgs(nil)},
+		{
+			name: "required fields",
+			args: &ClusterArgs{MachineUser: "ubuntu", NodeCount: 1, PrivateNetworkName: "internal"},
+			want: []string{"flavor: is required", "machineImage: is required"},
+		},
+		{
+			name: "machine user",
+			args: validArgs(func(args *ClusterArgs) { args.MachineUser = "Ubuntu User" }),
+			want: []string{`machineUser: "Ubuntu User" is not a valid user name`},
+		},
+		{
+			name: "machine user with underscore and digits",
+			args: validArgs(func(args *ClusterArgs) { args.MachineUser = "_k3s-user1" }),
+		},
+		{
+			name: "negative numbers",
+			args: validArgs(func(args *ClusterArgs) {
+				args.NodeCount = 0
+				args.VolumeSize = -1
+				args.SSHDialTimeout = -1
+			}),
+			want: []string{
+				"nodeCount: must be at least 1, got 0",
+				"volumeSize: must not be negative, got -1",
+				"sshDialTimeout: must not be negative, got -1",
+			},
+		},
+		{
+			name: "unlimited ssh dial retries",
+			args: validArgs(func(args *ClusterArgs) { args.SSHDialRetries = -1 }),
+		},
+		{
+			name: "ssh dial retries below unlimited",
+			args: validArgs(func(args *ClusterArgs) { args.SSHDialRetries = -2 }),
+			want: []string{"sshDialRetries: must be -1 for unlimited retries or not negative, got -2"},
+		},
+		{
+			name: "port range",
+			args: validArgs(func(args *ClusterArgs) { args.AdditionalPorts = []int{0, 1, 65535, 65536} }),
+			want: []string{"additionalPorts: 0 is not a valid port", "additionalPorts: 65536 is not a valid port"},
+		},
+		{
+			name: "ports overlapping the defaults",
+			args: validArgs(func(args *ClusterArgs) { args.AdditionalPorts = []int{22, 80, 443, KubeAPIServerPort, 8080} }),
+			want: []string{
+				"additionalPorts: 22 is always opened for ssh",
+				"additionalPorts: 80 is always opened for http",
+				"additionalPorts: 443 is always opened for https",
+				fmt.Sprintf("additionalPorts: %d is always opened for kube-apiserver", KubeAPIServerPort),
+			},
+		},
+		{
+			name: "private network missing",
+			args: validArgs(func(args *ClusterArgs) { args.PrivateNetworkName = "" }),
+			want: []string{"privateNetworkName: is required if public is not set"},
+		},
+		{
+			name: "private with public settings",
+			args: validArgs(func(args *ClusterArgs) { args.PublicNetworkName = "public" }),
+		},
+		{
+			name: "public settings missing",
+			args: validArgs(func(args *ClusterArgs) { args.Public = true }),
+			want: []string{
+				"publicIPPool: is required if public is set",
+				"publicNetworkName: is required if public is set",
+				"publicNetworkID: is required if public is set",
+			},
+		},
+		{
+			name: "public without private network",
+			args: validArgs(func(args *ClusterArgs) {
+				args.Public = true
+				args.PrivateNetworkName = ""
+				args.PublicIPPool = "pool"
+				args.PublicNetworkName = "public"
+				args.PublicNetworkID = "public-id"
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.args.Validate()
+			if got := problems(err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %q, want %q", got, tt.want)
+			}
+
+			if err != nil && !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("Validate() = %v, want it to match ErrInvalidConfig", err)
+			}
+		})
+	}
+}
+
+func TestFieldErrorIsInvalidConfig(t *testing.T) {
+	err := fmt.Errorf("spec 1: %w", errors.Join(
+		&FieldError{Field: "flavor", Problem: "is required"},
+		errors.New("other"),
+	))
+
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("errors.Is(%v, ErrInvalidConfig) = false, want true", err)
+	}
+
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "flavor" {
+		t.Errorf("errors.As(%v) = %v, want the FieldError of flavor", err, fieldErr)
+	}
+
+	if errors.Is(errors.Join(errors.New("other")), ErrInvalidConfig) {
+		t.Error("errors without a FieldError match ErrInvalidConfig")
+	}
+}
+
+// problems returns the messages of the joined errors.
+func problems(err error) []string {
+	if err == nil {
+		return nil
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []string{err.Error()}
+	}
+
+	var messages []string
+	for _, err := range joined.Unwrap() {
+		messages = append(messages, err.Error())
+	}
+
+	return messages
+}
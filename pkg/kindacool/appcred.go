@@ -0,0 +1,98 @@
+package kindacool
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/applicationcredentials"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/tokens"
+)
+
+// ApplicationCredentialOptions configures a new application credential.
+type ApplicationCredentialOptions struct {
+	Name        string
+	Description string
+	// ExpiresAt is the time the credential stops working. The zero value means that it never expires.
+	ExpiresAt time.Time
+	// Roles limits the credential to the given roles of the project. By default all roles of the user are used.
+	Roles []string
+	// Unrestricted allows the credential to create and delete other application credentials and trusts.
+	Unrestricted bool
+}
+
+// ApplicationCredential is a created application credential with everything needed to authenticate with it.
+type ApplicationCredential struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Secret    string     `json:"secret"`
+	AuthURL   string     `json:"authURL"`
+	Region    string     `json:"region,omitempty"`
+	ProjectID string     `json:"projectID"`
+	Roles     []string   `json:"roles,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// CreateApplicationCredential creates an application credential for the current user
+// that is scoped to the project of the cloud.
+// The secret is only returned once by OpenStack and can't be retrieved later.
+func (c *Cloud) CreateApplicationCredential(opts ApplicationCredentialOptions) (*ApplicationCredential, error) {
+	result, ok := c.provider.GetAuthResult().(tokens.CreateResult)
+	if !ok {
+		return nil, fmt.Errorf("%w: only identity v3 is supported", ErrNoProjectScope)
+	}
+
+	user, err := result.ExtractUser()
+	if err != nil {
+		return nil, err
+	}
+
+	projectID, err := c.projectID()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := openstack.NewIdentityV3(c.provider, c.endpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	createOpts := applicationcredentials.CreateOpts{
+		Name:         opts.Name,
+		Description:  opts.Description,
+		Unrestricted: opts.Unrestricted,
+	}
+
+	if !opts.ExpiresAt.IsZero() {
+		expiresAt := opts.ExpiresAt.UTC()
+		createOpts.ExpiresAt = &expiresAt
+	}
+
+	for _, role := range opts.Roles {
+		createOpts.Roles = append(createOpts.Roles, applicationcredentials.Role{Name: role})
+	}
+
+	created, err := applicationcredentials.Create(client, user.ID, createOpts).Extract()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the application credential: %w", err)
+	}
+
+	credential := &ApplicationCredential{
+		ID:        created.ID,
+		Name:      created.Name,
+		Secret:    created.Secret,
+		AuthURL:   c.credentials.AuthURL,
+		Region:    c.options.Region,
+		ProjectID: projectID,
+	}
+
+	for _, role := range created.Roles {
+		credential.Roles = append(credential.Roles, role.Name)
+	}
+
+	if !created.ExpiresAt.IsZero() {
+		credential.ExpiresAt = &created.ExpiresAt
+	}
+
+	return credential, nil
+}
@@ -0,0 +1,62 @@
+package kindacool
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// DestroyResult is the outcome of destroying a single cluster with Manager.DestroyMany.
+type DestroyResult struct {
+	Name string
+	Err  error
+}
+
+// ForCluster returns a copy of the manager that manages the cluster with the given name.
+// Its log lines are prefixed with the cluster name to be distinguishable from other clusters.
+// In JSON format the cluster name is part of every event instead.
+func (m *Manager) ForCluster(name string) *Manager {
+	clusterManager := *m
+	clusterManager.Options.Name = name
+	clusterManager.Logger = log.New(m.Logger.Writer(), fmt.Sprintf("[%s] ", name), 0)
+	if w, ok := m.Logger.Writer().(*jsonLogWriter); ok {
+		clusterManager.Logger = log.New(&jsonLogWriter{out: w.out, cluster: name}, "", 0)
+	}
+
+	return &clusterManager
+}
+
+// DestroyMany destroys all the given clusters with at most parallelism destroys running at the same time.
+// A failure doesn't stop the other destroys, instead the result for every cluster is returned
+// in the same order as the given names.
+func (m *Manager) DestroyMany(ctx context.Context, names []string, parallelism int) []DestroyResult {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	results := make([]DestroyResult, len(names))
+	semaphore := make(chan struct{}, parallelism)
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				results[i] = DestroyResult{Name: name, Err: ctx.Err()}
+				return
+			}
+
+			results[i] = DestroyResult{Name: name, Err: m.ForCluster(name).Destroy(ctx)}
+		}(i, name)
+	}
+
+	wg.Wait()
+
+	return results
+}
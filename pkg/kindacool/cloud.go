@@ -0,0 +1,259 @@
+package kindacool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/tokens"
+	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/images"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/external"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
+	"github.com/gophercloud/utils/openstack/clientconfig"
+)
+
+var ErrNoProjectScope = errors.New("the project of the token could not be determined")
+
+// CloudOptions selects the OpenStack cloud that is used for a cluster.
+type CloudOptions struct {
+	// Cloud is the name of an entry in clouds.yaml and defaults to $OS_CLOUD.
+	// Without a cloud the credentials are read from the OS_* environment variables of an .openrc file.
+	Cloud string `json:"cloud,omitempty"`
+	// Region defaults to the region of the cloud's entry or $OS_REGION_NAME.
+	Region string `json:"region,omitempty"`
+	// ApplicationCredentialID and ApplicationCredentialSecret replace the credentials of the cloud
+	// with an application credential, e.g. for CI jobs that should not use a user's password.
+	ApplicationCredentialID     string `json:"applicationCredentialID,omitempty"`
+	ApplicationCredentialSecret string `json:"-"`
+}
+
+// resolve fills in the defaults of the options.
+func (o CloudOptions) resolve() (CloudOptions, error) {
+	if o.Cloud == "" {
+		o.Cloud = os.Getenv("OS_CLOUD")
+	}
+
+	if o.Region == "" && o.Cloud != "" {
+		cloud, err := clientconfig.GetCloudFromYAML(&clientconfig.ClientOpts{Cloud: o.Cloud})
+		if err != nil {
+			return o, fmt.Errorf("%w: %v", ErrUnauthorized, err)
+		}
+
+		o.Region = cloud.RegionName
+	}
+
+	if o.Region == "" {
+		o.Region = os.Getenv("OS_REGION_NAME")
+	}
+
+	if o.ApplicationCredentialID != "" && o.ApplicationCredentialSecret == "" {
+		o.ApplicationCredentialSecret = os.Getenv("OS_APPLICATION_CREDENTIAL_SECRET")
+	}
+
+	return o, nil
+}
+
+// authOptions returns the credentials of the cloud.
+func (o CloudOptions) authOptions() (*gophercloud.AuthOptions, error) {
+	opts, err := clientconfig.AuthOptions(&clientconfig.ClientOpts{Cloud: o.Cloud, RegionName: o.Region})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+
+	if opts.IdentityEndpoint == "" {
+		return nil, fmt.Errorf("%w: no auth URL is set", ErrUnauthorized)
+	}
+
+	return opts, nil
+}
+
+// CloudNames returns the names of all clouds in clouds.yaml.
+func CloudNames() ([]string, error) {
+	clouds, err := clientconfig.LoadCloudsYAML()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(clouds))
+	for name := range clouds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Cloud gives access to the OpenStack resources that can be used for clusters.
+type Cloud struct {
+	provider    *gophercloud.ProviderClient
+	endpoints   gophercloud.EndpointOpts
+	options     CloudOptions
+	credentials *Credentials
+}
+
+// Network is an OpenStack network.
+type Network struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	External bool   `json:"external"`
+}
+
+// NewCloud authenticates against the selected OpenStack cloud.
+func NewCloud(ctx context.Context, options CloudOptions) (*Cloud, error) {
+	options, err := options.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := options.credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := openstack.NewClient(credentials.AuthURL)
+	if err != nil {
+		return nil, err
+	}
+	provider.Context = ctx
+
+	if provider.HTTPClient, err = credentials.httpClient(); err != nil {
+		return nil, err
+	}
+
+	if err := openstack.Authenticate(provider, credentials.authOptions()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+
+	return &Cloud{
+		provider:    provider,
+		endpoints:   gophercloud.EndpointOpts{Region: options.Region},
+		options:     options,
+		credentials: credentials,
+	}, nil
+}
+
+// Flavors returns the names of all flavors.
+func (c *Cloud) Flavors() ([]string, error) {
+	allFlavors, err := c.listFlavors()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(allFlavors))
+	for _, flavor := range allFlavors {
+		names = append(names, flavor.Name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// flavor returns the flavor with the given name or nil if it doesn't exist.
+func (c *Cloud) flavor(name string) (*flavors.Flavor, error) {
+	allFlavors, err := c.listFlavors()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range allFlavors {
+		if allFlavors[i].Name == name {
+			return &allFlavors[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (c *Cloud) listFlavors() ([]flavors.Flavor, error) {
+	client, err := openstack.NewComputeV2(c.provider, c.endpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := flavors.ListDetail(client, flavors.ListOpts{}).AllPages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list flavors: %w", err)
+	}
+
+	return flavors.ExtractFlavors(pages)
+}
+
+// Images returns the names of all active images.
+func (c *Cloud) Images() ([]string, error) {
+	client, err := openstack.NewImageServiceV2(c.provider, c.endpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := images.List(client, images.ListOpts{Status: images.ImageStatusActive}).AllPages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+
+	allImages, err := images.ExtractImages(pages)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(allImages))
+	for _, image := range allImages {
+		names = append(names, image.Name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Networks returns all networks that are visible for the project.
+// External networks can be used as public networks and IP pools.
+func (c *Cloud) Networks() ([]Network, error) {
+	client, err := openstack.NewNetworkV2(c.provider, c.endpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := networks.List(client, networks.ListOpts{}).AllPages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+
+	var allNetworks []struct {
+		networks.Network
+		external.NetworkExternalExt
+	}
+	if err := networks.ExtractNetworksInto(pages, &allNetworks); err != nil {
+		return nil, err
+	}
+
+	result := make([]Network, 0, len(allNetworks))
+	for _, network := range allNetworks {
+		result = append(result, Network{ID: network.ID, Name: network.Name, External: network.External})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result, nil
+}
+
+// projectID returns the ID of the project the token is scoped to.
+func (c *Cloud) projectID() (string, error) {
+	result, ok := c.provider.GetAuthResult().(tokens.CreateResult)
+	if !ok {
+		return "", fmt.Errorf("%w: only identity v3 is supported", ErrNoProjectScope)
+	}
+
+	project, err := result.ExtractProject()
+	if err != nil {
+		return "", err
+	}
+
+	if project == nil {
+		return "", fmt.Errorf("%w: the token is not scoped to a project", ErrNoProjectScope)
+	}
+
+	return project.ID, nil
+}
@@ -0,0 +1,223 @@
+package kindacool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// stack config keys that record the cloud of a cluster.
+// The region is also read by pulumi's default OpenStack provider of clusters
+// that were created before the provider was passed explicitly.
+const (
+	configCloud          = "kindacool:cloud"
+	configRegion         = "openstack:region"
+	configAuthURL        = "kindacool:authURL"
+	configProjectID      = "kindacool:projectID"
+	configCredentials    = "kindacool:credentials"
+	configLegacyProvider = "kindacool:legacyProvider"
+)
+
+// defaultProviderType is the type of the default OpenStack provider in the stack state.
+const defaultProviderType = "pulumi:providers:openstack"
+
+var ErrCloudMismatch = errors.New("the cluster was created in another cloud")
+
+// CloudRecord is the OpenStack cloud a cluster was created in.
+// It is stored in the stack config of every update.
+type CloudRecord struct {
+	Cloud     string `json:"cloud,omitempty"`
+	Region    string `json:"region,omitempty"`
+	AuthURL   string `json:"authURL,omitempty"`
+	ProjectID string `json:"projectID,omitempty"`
+}
+
+// Record returns the identity of the cloud that is stored for clusters.
+func (c *Cloud) Record() CloudRecord {
+	// the project is only used to detect mismatches, so it's not required
+	projectID, _ := c.projectID()
+
+	return CloudRecord{
+		Cloud:     c.options.Cloud,
+		Region:    c.options.Region,
+		AuthURL:   c.credentials.AuthURL,
+		ProjectID: projectID,
+	}
+}
+
+// recordFromConfig reads the record from the config of a stack update.
+// It is empty for clusters that were created by older versions.
+func recordFromConfig(config auto.ConfigMap) *CloudRecord {
+	record := &CloudRecord{
+		Cloud:     config[configCloud].Value,
+		Region:    config[configRegion].Value,
+		AuthURL:   config[configAuthURL].Value,
+		ProjectID: config[configProjectID].Value,
+	}
+
+	if *record == (CloudRecord{}) {
+		return nil
+	}
+
+	return record
+}
+
+// clusterCloud connects to the cloud the current cluster was created in.
+// For new clusters and clusters of older versions the cloud of the options is used.
+// If the options select another cloud than the one the cluster was created in, an error is returned.
+func (m *Manager) clusterCloud(ctx context.Context) (*Cloud, error) {
+	recorded, err := m.recordedCloud(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	options := m.Options.Cloud
+	if recorded.Cloud != "" {
+		if options.Cloud != "" && options.Cloud != recorded.Cloud {
+			return nil, fmt.Errorf("%w: it uses %q from clouds.yaml, not %q", ErrCloudMismatch, recorded.Cloud, options.Cloud)
+		}
+
+		options.Cloud = recorded.Cloud
+	}
+
+	if recorded.Region != "" {
+		if options.Region != "" && options.Region != recorded.Region {
+			return nil, fmt.Errorf("%w: it uses the region %q, not %q", ErrCloudMismatch, recorded.Region, options.Region)
+		}
+
+		options.Region = recorded.Region
+	}
+
+	cloud, err := NewCloud(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	current := cloud.Record()
+	if recorded.AuthURL != "" && (current.AuthURL != recorded.AuthURL || current.ProjectID != recorded.ProjectID) {
+		return nil, fmt.Errorf(
+			"%w: it was created with %s in project %s, but the credentials are for %s in project %s",
+			ErrCloudMismatch, recorded.AuthURL, recorded.ProjectID, current.AuthURL, current.ProjectID,
+		)
+	}
+
+	return cloud, nil
+}
+
+// recordedCloud returns the cloud of the current cluster's last update.
+func (m *Manager) recordedCloud(ctx context.Context) (CloudRecord, error) {
+	s, err := m.selectStack(ctx)
+	if err != nil {
+		if auto.IsSelectStack404Error(err) {
+			return CloudRecord{}, nil
+		}
+
+		return CloudRecord{}, err
+	}
+
+	history, err := s.History(ctx, 1, 1)
+	if err != nil {
+		return CloudRecord{}, err
+	}
+
+	if len(history) == 0 {
+		return CloudRecord{}, nil
+	}
+
+	if record := recordFromConfig(history[0].Config); record != nil {
+		return *record, nil
+	}
+
+	return CloudRecord{}, nil
+}
+
+// pinCloud stores the cloud and its credentials in the stack config and makes sure that pulumi's OpenStack providers use it.
+// The OS_* environment variables are cleared for pulumi, since the providers would otherwise
+// mix them with the stored credentials, e.g. prefer a password over an application credential.
+func pinCloud(ctx context.Context, s auto.Stack, cloud *Cloud) error {
+	record := cloud.Record()
+
+	credentials, err := json.Marshal(cloud.credentials)
+	if err != nil {
+		return err
+	}
+
+	config := auto.ConfigMap{
+		configAuthURL:     auto.ConfigValue{Value: record.AuthURL},
+		configProjectID:   auto.ConfigValue{Value: record.ProjectID},
+		configRegion:      auto.ConfigValue{Value: record.Region},
+		configCredentials: auto.ConfigValue{Value: string(credentials), Secret: true},
+	}
+	if record.Cloud != "" {
+		config[configCloud] = auto.ConfigValue{Value: record.Cloud}
+	}
+
+	for key, value := range cloud.credentials.providerConfig() {
+		config[key] = value
+	}
+
+	legacyProvider, err := defaultProviderURN(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	if legacyProvider != "" {
+		config[configLegacyProvider] = auto.ConfigValue{Value: legacyProvider}
+	}
+
+	if err := s.SetAllConfig(ctx, config); err != nil {
+		return fmt.Errorf("failed to store the cloud in the stack config: %w", err)
+	}
+
+	return clearOpenStackEnv(s.Workspace())
+}
+
+// clearOpenStackEnv clears the OS_* environment variables for pulumi,
+// so that the OpenStack providers only use the credentials they are configured with.
+func clearOpenStackEnv(w auto.Workspace) error {
+	env := map[string]string{}
+	for _, variable := range os.Environ() {
+		if key, _, _ := strings.Cut(variable, "="); strings.HasPrefix(key, "OS_") {
+			env[key] = ""
+		}
+	}
+
+	return w.SetEnvVars(env)
+}
+
+// defaultProviderURN returns the URN of the default OpenStack provider in the stack's state.
+// It is empty if all resources already use an explicit provider.
+func defaultProviderURN(ctx context.Context, s auto.Stack) (string, error) {
+	state, err := s.Export(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to export the stack state: %w", err)
+	}
+
+	if len(state.Deployment) == 0 {
+		return "", nil
+	}
+
+	var deployment struct {
+		Resources []struct {
+			URN  string `json:"urn"`
+			Type string `json:"type"`
+		} `json:"resources"`
+	}
+	if err := json.Unmarshal(state.Deployment, &deployment); err != nil {
+		return "", fmt.Errorf("failed to decode the stack state: %w", err)
+	}
+
+	for _, resource := range deployment.Resources {
+		name := resource.URN[strings.LastIndex(resource.URN, "::")+len("::"):]
+		if resource.Type == defaultProviderType && strings.HasPrefix(name, "default") {
+			return resource.URN, nil
+		}
+	}
+
+	return "", nil
+}
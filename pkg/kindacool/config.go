@@ -1,15 +1,87 @@
 package kindacool
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"regexp"
 
-var ErrInvalidConfig = errors.New("invalid config")
+	"github.com/brumhard/kindacool/pkg/k3s"
+)
+
+const (
+	// namePattern only allows names that can be used in hostnames and OpenStack resource names.
+	namePattern = `^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// maxNameLength keeps the node names <name>-node-<index> within the 63 characters of a hostname.
+	maxNameLength = 50
+)
+
+// ErrInvalidConfig is defined in the k3s package to be usable in the cluster args' validation.
+var ErrInvalidConfig = k3s.ErrInvalidConfig
+
+// FieldError describes a problem with a single field of the configuration.
+type FieldError = k3s.FieldError
 
 type GlobalOptions struct {
 	Verbose bool
 	Name    string
+	// Cloud selects the OpenStack cloud for new clusters.
+	// Existing clusters always use the cloud they were created in.
+	Cloud CloudOptions
+	// Pulumi configures the managed pulumi CLI that is installed if there's none in $PATH.
+	Pulumi PulumiOptions
+	// Plugins configures the versions and the source of the pulumi plugins.
+	Plugins PluginOptions
 }
 
+// Validate checks the options that are shared by all clusters of a command and returns all problems at once.
+// The name is checked separately, since commands for spec files manage multiple clusters.
 func (o *GlobalOptions) Validate() error {
-	// TODO: validate name length
+	return errors.Join(o.Plugins.validate()...)
+}
+
+// ValidateName checks that the name of the cluster is set.
+// It's only checked for its presence, so that clusters with names that don't match
+// the rules for new clusters can still be managed, see validateNewName.
+func (o *GlobalOptions) ValidateName() error {
+	if o.Name == "" {
+		return &FieldError{Field: "name", Problem: "is required"}
+	}
+
 	return nil
 }
+
+// validateNewName checks that the name can be used for the hostnames and OpenStack resources of a new cluster.
+// An empty name is reported by ValidateName.
+func (o *GlobalOptions) validateNewName() error {
+	if o.Name == "" {
+		return nil
+	}
+
+	var errs []error
+
+	if len(o.Name) > maxNameLength {
+		errs = append(errs, &FieldError{
+			Field:   "name",
+			Problem: fmt.Sprintf("%q is %d characters long, at most %d are allowed", o.Name, len(o.Name), maxNameLength),
+		})
+	}
+
+	if !regexp.MustCompile(namePattern).MatchString(o.Name) {
+		errs = append(errs, &FieldError{
+			Field:   "name",
+			Problem: fmt.Sprintf("%q must consist of lower case letters, digits and '-' and start and end with a letter or digit", o.Name),
+		})
+	}
+
+	return errors.Join(errs...)
+}
+
+// DefaultClusterArgs returns the args that are used for all settings that are not set explicitly.
+func DefaultClusterArgs() k3s.ClusterArgs {
+	return k3s.ClusterArgs{
+		MachineFlavor: "m4.large",
+		NodeCount:     1,
+		MachineImage:  "Ubuntu 22.04",
+		MachineUser:   "ubuntu",
+	}
+}
@@ -0,0 +1,136 @@
+package kindacool
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGlobalOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options GlobalOptions
+		want    []string
+	}{
+		{name: "valid", options: GlobalOptions{Name: "kindacool"}},
+		// the names of the clusters of spec files are checked per cluster
+		{name: "without name", options: GlobalOptions{}},
+		{
+			name:    "plugin versions",
+			options: GlobalOptions{Name: "kindacool", Plugins: PluginOptions{Versions: map[string]string{"openstack": "v3.15.0"}}},
+		},
+		{
+			name: "invalid plugin versions",
+			options: GlobalOptions{Plugins: PluginOptions{Versions: map[string]string{
+				"openstack": "latest",
+				"aws":       "v6.0.0",
+			}}},
+			want: []string{
+				`pluginVersion: "aws" is not a required plugin`,
+				`pluginVersion: "latest" of openstack is invalid: No Major.Minor.Patch elements found`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.Validate()
+			if got := problems(err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %q, want %q", got, tt.want)
+			}
+
+			if err != nil && !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("Validate() = %v, want it to match ErrInvalidConfig", err)
+			}
+		})
+	}
+}
+
+func TestGlobalOptionsValidateName(t *testing.T) {
+	tests := []struct {
+		name        string
+		clusterName string
+		wantErr     bool
+	}{
+		{name: "valid", clusterName: "kindacool"},
+		{name: "required", wantErr: true},
+		// clusters of older versions may have names that are invalid for new clusters
+		{name: "name of an existing cluster", clusterName: "My_Cluster." + strings.Repeat("a", maxNameLength)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := GlobalOptions{Name: tt.clusterName}
+			err := options.ValidateName()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateName() = %v, want error %v", err, tt.wantErr)
+			}
+
+			if err != nil && !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("ValidateName() = %v, want it to match ErrInvalidConfig", err)
+			}
+		})
+	}
+}
+
+func TestGlobalOptionsValidateNewName(t *testing.T) {
+	patternProblem := "must consist of lower case letters, digits and '-' and start and end with a letter or digit"
+
+	tests := []struct {
+		name        string
+		clusterName string
+		want        []string
+	}{
+		{name: "empty is reported by ValidateName"},
+		{name: "single character", clusterName: "a"},
+		{name: "digits", clusterName: "1-2"},
+		{name: "dashes", clusterName: "my-k3s-cluster"},
+		{name: "maximum length", clusterName: strings.Repeat("a", maxNameLength)},
+		{
+			name:        "too long",
+			clusterName: strings.Repeat("a", maxNameLength+1),
+			want:        []string{`name: "` + strings.Repeat("a", maxNameLength+1) + `" is 51 characters long, at most 50 are allowed`},
+		},
+		{name: "upper case", clusterName: "Cluster", want: []string{`name: "Cluster" ` + patternProblem}},
+		{name: "leading dash", clusterName: "-cluster", want: []string{`name: "-cluster" ` + patternProblem}},
+		{name: "trailing dash", clusterName: "cluster-", want: []string{`name: "cluster-" ` + patternProblem}},
+		{name: "dot", clusterName: "my.cluster", want: []string{`name: "my.cluster" ` + patternProblem}},
+		{
+			name:        "too long and invalid",
+			clusterName: strings.Repeat("A", maxNameLength+1),
+			want: []string{
+				`name: "` + strings.Repeat("A", maxNameLength+1) + `" is 51 characters long, at most 50 are allowed`,
+				`name: "` + strings.Repeat("A", maxNameLength+1) + `" ` + patternProblem,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := GlobalOptions{Name: tt.clusterName}
+			if got := problems(options.validateNewName()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateNewName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// problems returns the messages of the joined errors.
+func problems(err error) []string {
+	if err == nil {
+		return nil
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []string{err.Error()}
+	}
+
+	var messages []string
+	for _, err := range joined.Unwrap() {
+		messages = append(messages, err.Error())
+	}
+
+	return messages
+}
@@ -0,0 +1,222 @@
+package kindacool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/brumhard/kindacool/pkg/k3s"
+
+	"github.com/pkg/sftp"
+)
+
+var ErrIsDirectory = errors.New("is a directory, set recursive to copy it")
+
+// CopyResult is the outcome of copying files to or from a single node.
+type CopyResult struct {
+	Node k3s.Node
+	Err  error
+}
+
+// Upload copies the local file or directory to the remote path on all given nodes at the same time.
+// If the remote path is an existing directory, the file is copied into it.
+func (a *NodeAccess) Upload(ctx context.Context, nodes []k3s.Node, localPath, remotePath string, recursive bool) []CopyResult {
+	return a.forEachNode(ctx, nodes, func(client *sftp.Client, node k3s.Node) error {
+		return upload(client, localPath, remotePath, recursive)
+	})
+}
+
+// Download copies the remote file or directory of all given nodes to the local path at the same time.
+// If the local path is an existing directory, the file is copied into it.
+// For multiple nodes the files of every node are copied into a subdirectory with the node's name.
+func (a *NodeAccess) Download(ctx context.Context, nodes []k3s.Node, remotePath, localPath string, recursive bool) []CopyResult {
+	return a.forEachNode(ctx, nodes, func(client *sftp.Client, node k3s.Node) error {
+		target := localPath
+		if len(nodes) > 1 {
+			target = filepath.Join(localPath, node.Name)
+			//nolint:gomnd // well-known directory permissions
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		}
+
+		return download(client, remotePath, target, recursive)
+	})
+}
+
+func (a *NodeAccess) forEachNode(
+	ctx context.Context, nodes []k3s.Node, copyFunc func(client *sftp.Client, node k3s.Node) error,
+) []CopyResult {
+	results := make([]CopyResult, len(nodes))
+
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node k3s.Node) {
+			defer wg.Done()
+			results[i] = CopyResult{Node: node, Err: a.withSFTP(ctx, node, copyFunc)}
+		}(i, node)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (a *NodeAccess) withSFTP(ctx context.Context, node k3s.Node, copyFunc func(client *sftp.Client, node k3s.Node) error) error {
+	sshClient, err := a.Dial(ctx, node)
+	if err != nil {
+		return err
+	}
+	defer sshClient.Close()
+
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		return fmt.Errorf("failed to start sftp: %w", err)
+	}
+	defer client.Close()
+
+	return copyFunc(client, node)
+}
+
+func upload(client *sftp.Client, localPath, remotePath string, recursive bool) error {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+
+	target := remotePath
+	if remoteInfo, err := client.Stat(remotePath); err == nil && remoteInfo.IsDir() {
+		target = path.Join(remotePath, filepath.Base(localPath))
+	}
+
+	if !info.IsDir() {
+		return uploadFile(client, localPath, target, info.Mode())
+	}
+
+	if !recursive {
+		return fmt.Errorf("%s %w", localPath, ErrIsDirectory)
+	}
+
+	return filepath.WalkDir(localPath, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relative, err := filepath.Rel(localPath, file)
+		if err != nil {
+			return err
+		}
+
+		destination := path.Join(target, filepath.ToSlash(relative))
+		if entry.IsDir() {
+			return client.MkdirAll(destination)
+		}
+
+		// symlinks and other special files are skipped
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		return uploadFile(client, file, destination, info.Mode())
+	})
+}
+
+func uploadFile(client *sftp.Client, localFile, remoteFile string, mode fs.FileMode) error {
+	in, err := os.Open(localFile)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := client.OpenFile(remoteFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", remoteFile, err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return fmt.Errorf("failed to write %s: %w", remoteFile, err)
+	}
+
+	return client.Chmod(remoteFile, mode.Perm())
+}
+
+func download(client *sftp.Client, remotePath, localPath string, recursive bool) error {
+	info, err := client.Stat(remotePath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", remotePath, err)
+	}
+
+	target := localPath
+	if localInfo, err := os.Stat(localPath); err == nil && localInfo.IsDir() {
+		target = filepath.Join(localPath, path.Base(remotePath))
+	}
+
+	if !info.IsDir() {
+		return downloadFile(client, remotePath, target, info.Mode())
+	}
+
+	if !recursive {
+		return fmt.Errorf("%s %w", remotePath, ErrIsDirectory)
+	}
+
+	walker := client.Walk(remotePath)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			return err
+		}
+
+		destination := filepath.Join(target, filepath.FromSlash(remoteRel(remotePath, walker.Path())))
+		switch {
+		case walker.Stat().IsDir():
+			//nolint:gomnd // well-known directory permissions
+			if err := os.MkdirAll(destination, 0755); err != nil {
+				return err
+			}
+		case walker.Stat().Mode().IsRegular():
+			if err := downloadFile(client, walker.Path(), destination, walker.Stat().Mode()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// remoteRel returns the path of the remote file relative to the base directory.
+// Remote paths always use slashes, independent of the local OS, so filepath.Rel can't be used.
+func remoteRel(base, file string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(path.Clean(file), path.Clean(base)), "/")
+}
+
+func downloadFile(client *sftp.Client, remoteFile, localFile string, mode fs.FileMode) error {
+	in, err := client.Open(remoteFile)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", remoteFile, err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(localFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return fmt.Errorf("failed to read %s: %w", remoteFile, err)
+	}
+
+	return nil
+}
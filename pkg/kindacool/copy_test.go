@@ -0,0 +1,23 @@
+package kindacool
+
+import "testing"
+
+func TestRemoteRel(t *testing.T) {
+	tests := []struct {
+		base string
+		file string
+		want string
+	}{
+		{base: "/var/log", file: "/var/log", want: ""},
+		{base: "/var/log/", file: "/var/log/pods/kube-system", want: "pods/kube-system"},
+		{base: "./logs", file: "logs/k3s.log", want: "k3s.log"},
+		{base: "./logs", file: "./logs", want: ""},
+		{base: "/", file: "/etc/rancher", want: "etc/rancher"},
+	}
+
+	for _, tt := range tests {
+		if got := remoteRel(tt.base, tt.file); got != tt.want {
+			t.Errorf("remoteRel(%q, %q) = %q, want %q", tt.base, tt.file, got, tt.want)
+		}
+	}
+}
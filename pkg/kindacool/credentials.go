@@ -0,0 +1,278 @@
+package kindacool
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/utils/openstack/clientconfig"
+	"github.com/pulumi/pulumi-openstack/sdk/v3/go/openstack"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+var ErrInvalidCACert = errors.New("the CA certificate contains no valid PEM certificates")
+
+// Credentials are the settings kindacool passes to the OpenStack provider of a cluster.
+// They are stored as a secret in the stack config, so that every update uses the credentials it was started with.
+type Credentials struct {
+	AuthURL                     string `json:"authURL"`
+	Region                      string `json:"region,omitempty"`
+	ProjectID                   string `json:"projectID,omitempty"`
+	ProjectName                 string `json:"projectName,omitempty"`
+	ProjectDomainID             string `json:"projectDomainID,omitempty"`
+	ProjectDomainName           string `json:"projectDomainName,omitempty"`
+	UserID                      string `json:"userID,omitempty"`
+	Username                    string `json:"username,omitempty"`
+	UserDomainID                string `json:"userDomainID,omitempty"`
+	UserDomainName              string `json:"userDomainName,omitempty"`
+	Password                    string `json:"password,omitempty"`
+	ApplicationCredentialID     string `json:"applicationCredentialID,omitempty"`
+	ApplicationCredentialName   string `json:"applicationCredentialName,omitempty"`
+	ApplicationCredentialSecret string `json:"applicationCredentialSecret,omitempty"`
+	// CACert is the PEM encoded CA bundle to verify the OpenStack APIs with.
+	CACert   string `json:"caCert,omitempty"`
+	Insecure bool   `json:"insecure,omitempty"`
+}
+
+// credentials collects the credentials of the resolved options from clouds.yaml or the environment.
+func (o CloudOptions) credentials() (*Credentials, error) {
+	opts, err := o.authOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := &Credentials{
+		AuthURL:                     opts.IdentityEndpoint,
+		Region:                      o.Region,
+		ProjectID:                   opts.TenantID,
+		ProjectName:                 opts.TenantName,
+		UserID:                      opts.UserID,
+		Username:                    opts.Username,
+		UserDomainID:                opts.DomainID,
+		UserDomainName:              opts.DomainName,
+		Password:                    opts.Password,
+		ApplicationCredentialID:     opts.ApplicationCredentialID,
+		ApplicationCredentialName:   opts.ApplicationCredentialName,
+		ApplicationCredentialSecret: opts.ApplicationCredentialSecret,
+	}
+
+	if scope := opts.Scope; scope != nil {
+		credentials.ProjectID = scope.ProjectID
+		credentials.ProjectName = scope.ProjectName
+		credentials.ProjectDomainID = scope.DomainID
+		credentials.ProjectDomainName = scope.DomainName
+	}
+
+	if o.ApplicationCredentialID != "" {
+		credentials.ApplicationCredentialID = o.ApplicationCredentialID
+		credentials.ApplicationCredentialName = ""
+		credentials.ApplicationCredentialSecret = o.ApplicationCredentialSecret
+	}
+
+	if credentials.ApplicationCredentialID != "" {
+		// the ID identifies the user and project, other settings would only take precedence over it
+		*credentials = Credentials{
+			AuthURL:                     credentials.AuthURL,
+			Region:                      credentials.Region,
+			ApplicationCredentialID:     credentials.ApplicationCredentialID,
+			ApplicationCredentialSecret: credentials.ApplicationCredentialSecret,
+		}
+	}
+
+	if credentials.ApplicationCredentialSecret == "" &&
+		(credentials.ApplicationCredentialID != "" || credentials.ApplicationCredentialName != "") {
+		return nil, fmt.Errorf("%w: the secret of the application credential is not set", ErrUnauthorized)
+	}
+
+	caCertFile := os.Getenv("OS_CACERT")
+	credentials.Insecure, _ = strconv.ParseBool(os.Getenv("OS_INSECURE"))
+
+	if o.Cloud != "" {
+		cloud, err := clientconfig.GetCloudFromYAML(&clientconfig.ClientOpts{Cloud: o.Cloud, RegionName: o.Region})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnauthorized, err)
+		}
+
+		if cloud.CACertFile != "" {
+			caCertFile = cloud.CACertFile
+		}
+
+		if cloud.Verify != nil {
+			credentials.Insecure = !*cloud.Verify
+		}
+	}
+
+	if caCertFile != "" {
+		caCert, err := os.ReadFile(caCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA certificate: %w", err)
+		}
+
+		credentials.CACert = string(caCert)
+	}
+
+	return credentials, nil
+}
+
+// authOptions returns the options to authenticate against the identity service with gophercloud.
+func (c *Credentials) authOptions() gophercloud.AuthOptions {
+	opts := gophercloud.AuthOptions{
+		IdentityEndpoint:            c.AuthURL,
+		UserID:                      c.UserID,
+		Username:                    c.Username,
+		DomainID:                    c.UserDomainID,
+		DomainName:                  c.UserDomainName,
+		Password:                    c.Password,
+		ApplicationCredentialID:     c.ApplicationCredentialID,
+		ApplicationCredentialName:   c.ApplicationCredentialName,
+		ApplicationCredentialSecret: c.ApplicationCredentialSecret,
+		AllowReauth:                 true,
+	}
+
+	// application credentials are always scoped to the project they were created in
+	if c.ApplicationCredentialID == "" && c.ApplicationCredentialName == "" {
+		opts.Scope = &gophercloud.AuthScope{
+			ProjectID:   c.ProjectID,
+			ProjectName: c.ProjectName,
+			DomainID:    c.ProjectDomainID,
+			DomainName:  c.ProjectDomainName,
+		}
+	}
+
+	return opts
+}
+
+// httpClient returns a client that verifies the OpenStack APIs like the provider does.
+func (c *Credentials) httpClient() (http.Client, error) {
+	//nolint:gosec // skipping the verification is explicitly configured by the user
+	tlsConfig := &tls.Config{InsecureSkipVerify: c.Insecure}
+
+	if c.CACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(c.CACert)) {
+			return http.Client{}, ErrInvalidCACert
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return http.Client{}, nil
+	}
+
+	transport = transport.Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return http.Client{Transport: transport}, nil
+}
+
+// providerArgs returns the args for the explicit OpenStack provider of a cluster.
+// All settings are passed explicitly, so that the provider doesn't fall back to the environment of the pulumi program.
+func (c *Credentials) providerArgs() *openstack.ProviderArgs {
+	return &openstack.ProviderArgs{
+		AuthUrl:                     pulumi.String(c.AuthURL),
+		Region:                      pulumi.String(c.Region),
+		TenantId:                    pulumi.String(c.ProjectID),
+		TenantName:                  pulumi.String(c.ProjectName),
+		ProjectDomainId:             pulumi.String(c.ProjectDomainID),
+		ProjectDomainName:           pulumi.String(c.ProjectDomainName),
+		UserId:                      pulumi.String(c.UserID),
+		UserName:                    pulumi.String(c.Username),
+		UserDomainId:                pulumi.String(c.UserDomainID),
+		UserDomainName:              pulumi.String(c.UserDomainName),
+		Password:                    pulumi.ToSecret(pulumi.String(c.Password)).(pulumi.StringOutput),
+		ApplicationCredentialId:     pulumi.String(c.ApplicationCredentialID),
+		ApplicationCredentialName:   pulumi.String(c.ApplicationCredentialName),
+		ApplicationCredentialSecret: pulumi.ToSecret(pulumi.String(c.ApplicationCredentialSecret)).(pulumi.StringOutput),
+		// the provider accepts the contents of the certificate as well as a path
+		CacertFile:  pulumi.String(c.CACert),
+		Insecure:    pulumi.Bool(c.Insecure),
+		AllowReauth: pulumi.Bool(true),
+		Cloud:       pulumi.String(""),
+	}
+}
+
+// providerConfig returns the stack config for pulumi's default OpenStack provider.
+// It is only used by clusters that were created before the provider was passed explicitly.
+func (c *Credentials) providerConfig() auto.ConfigMap {
+	config := auto.ConfigMap{
+		"openstack:insecure": auto.ConfigValue{Value: strconv.FormatBool(c.Insecure)},
+	}
+
+	for key, value := range map[string]string{
+		"openstack:authUrl":                   c.AuthURL,
+		"openstack:tenantId":                  c.ProjectID,
+		"openstack:tenantName":                c.ProjectName,
+		"openstack:projectDomainId":           c.ProjectDomainID,
+		"openstack:projectDomainName":         c.ProjectDomainName,
+		"openstack:userId":                    c.UserID,
+		"openstack:userName":                  c.Username,
+		"openstack:userDomainId":              c.UserDomainID,
+		"openstack:userDomainName":            c.UserDomainName,
+		"openstack:applicationCredentialId":   c.ApplicationCredentialID,
+		"openstack:applicationCredentialName": c.ApplicationCredentialName,
+		"openstack:cacertFile":                c.CACert,
+	} {
+		if value != "" {
+			config[key] = auto.ConfigValue{Value: value}
+		}
+	}
+
+	for key, value := range map[string]string{
+		"openstack:password":                    c.Password,
+		"openstack:applicationCredentialSecret": c.ApplicationCredentialSecret,
+	} {
+		if value != "" {
+			config[key] = auto.ConfigValue{Value: value, Secret: true}
+		}
+	}
+
+	return config
+}
+
+// providerFunc creates the OpenStack provider of a cluster's program with the given name and plugin version.
+type providerFunc func(ctx *pulumi.Context, name, version string) (*openstack.Provider, error)
+
+// providerFromConfig creates the OpenStack provider with the credentials that are stored in the stack config.
+func providerFromConfig(ctx *pulumi.Context, name, version string) (*openstack.Provider, error) {
+	rawCredentials, ok := ctx.GetConfig(configCredentials)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not set in the stack config", ErrUnauthorized, configCredentials)
+	}
+
+	credentials := &Credentials{}
+	if err := json.Unmarshal([]byte(rawCredentials), credentials); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", configCredentials, err)
+	}
+
+	legacyProvider, _ := ctx.GetConfig(configLegacyProvider)
+
+	return newProvider(ctx, name, version, credentials, legacyProvider)
+}
+
+// newProvider creates the OpenStack provider with the given credentials.
+// The alias of the legacy provider keeps clusters that were created with the default provider from being replaced.
+// The provider uses the given plugin version or the version of the SDK if it's empty.
+func newProvider(
+	ctx *pulumi.Context, name, version string, credentials *Credentials, legacyProvider string,
+) (*openstack.Provider, error) {
+	var opts []pulumi.ResourceOption
+	if version != "" {
+		// the SDK pins its own version on the provider, the option overrides it
+		opts = append(opts, pulumi.Version(version))
+	}
+
+	if legacyProvider != "" {
+		opts = append(opts, pulumi.Aliases([]pulumi.Alias{{URN: pulumi.URN(legacyProvider)}}))
+	}
+
+	return openstack.NewProvider(ctx, name, credentials.providerArgs(), opts...)
+}
@@ -0,0 +1,96 @@
+package kindacool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/brumhard/kindacool/pkg/k3s"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// ClusterDescription contains all the information about a cluster
+// that can be obtained from its stack.
+type ClusterDescription struct {
+	Name               string                  `json:"name"`
+	Cloud              *CloudRecord            `json:"cloud,omitempty"`
+	Args               *k3s.ClusterArgs        `json:"args,omitempty"`
+	K3sVersion         string                  `json:"k3sVersion,omitempty"`
+	Nodes              []k3s.Node              `json:"nodes,omitempty"`
+	NetworkIDs         map[string]string       `json:"networkIDs,omitempty"`
+	SecurityGroupRules []k3s.SecurityGroupRule `json:"securityGroupRules,omitempty"`
+	LastUpdate         string                  `json:"lastUpdate,omitempty"`
+	LastResult         string                  `json:"lastResult,omitempty"`
+}
+
+// Describe collects the details of the current cluster from the stack outputs and history.
+func (m *Manager) Describe(ctx context.Context) (*ClusterDescription, error) {
+	stack, err := m.selectStack(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs, err := stack.Outputs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	description := &ClusterDescription{Name: m.Options.Name}
+
+	if argsJSON, ok := outputs[OutputClusterArgs].Value.(string); ok {
+		description.Args = &k3s.ClusterArgs{}
+		if err := json.Unmarshal([]byte(argsJSON), description.Args); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", OutputClusterArgs, err)
+		}
+	}
+
+	description.K3sVersion, _ = outputs[OutputK3sVersion].Value.(string)
+
+	for key, target := range map[string]interface{}{
+		OutputNodes:              &description.Nodes,
+		OutputNetworkIDs:         &description.NetworkIDs,
+		OutputSecurityGroupRules: &description.SecurityGroupRules,
+	} {
+		if err := decodeOutput(outputs, key, target); err != nil {
+			return nil, err
+		}
+	}
+
+	history, err := stack.History(ctx, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(history) > 0 {
+		description.LastUpdate = history[0].StartTime
+		if history[0].EndTime != nil {
+			description.LastUpdate = *history[0].EndTime
+		}
+		description.LastResult = history[0].Result
+		description.Cloud = recordFromConfig(history[0].Config)
+	}
+
+	return description, nil
+}
+
+// decodeOutput converts the structured output with the given key into target.
+// Outputs that don't exist are skipped since clusters created by older versions
+// don't export all of them.
+func decodeOutput(outputs auto.OutputMap, key string, target interface{}) error {
+	output, ok := outputs[key]
+	if !ok || output.Value == nil {
+		return nil
+	}
+
+	raw, err := json.Marshal(output.Value)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", key, err)
+	}
+
+	if err := json.Unmarshal(raw, target); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", key, err)
+	}
+
+	return nil
+}
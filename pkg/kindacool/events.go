@@ -0,0 +1,163 @@
+package kindacool
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// LogFormat defines how the manager writes its log output.
+type LogFormat string
+
+const (
+	LogFormatText LogFormat = "text"
+	LogFormatJSON LogFormat = "json"
+)
+
+// Stages of the manager's operations that are reported as events.
+const (
+	StageEnvironment = "environment"
+	StageStack       = "stack"
+	StagePlugins     = "plugins"
+	StagePreflight   = "preflight"
+	StageRefresh     = "refresh"
+	StageUp          = "up"
+	StageDestroy     = "destroy"
+	StageRetry       = "retry"
+	StageWait        = "wait"
+	StagePreview     = "preview"
+)
+
+// Types of the events in the JSON log output.
+const (
+	EventLog      = "log"
+	EventStage    = "stage"
+	EventResource = "resource"
+	EventSummary  = "summary"
+)
+
+// States of a resource operation in a resource event.
+const (
+	ResourceStarted = "started"
+	ResourceDone    = "done"
+	ResourceFailed  = "failed"
+)
+
+// Event is a single line of the JSON log output.
+type Event struct {
+	Time     time.Time         `json:"time"`
+	Type     string            `json:"type"`
+	Cluster  string            `json:"cluster,omitempty"`
+	Stage    string            `json:"stage,omitempty"`
+	Message  string            `json:"message,omitempty"`
+	Resource *ResourceEvent    `json:"resource,omitempty"`
+	Summary  *OperationSummary `json:"summary,omitempty"`
+}
+
+// ResourceEvent describes the state of an operation on a single resource.
+type ResourceEvent struct {
+	Op             apitype.OpType `json:"op"`
+	Type           string         `json:"type"`
+	Name           string         `json:"name"`
+	State          string         `json:"state"`
+	ElapsedSeconds float64        `json:"elapsedSeconds,omitempty"`
+	Error          string         `json:"error,omitempty"`
+}
+
+// OperationSummary contains the changes of a finished pulumi operation.
+type OperationSummary struct {
+	Changes         map[apitype.OpType]int `json:"changes"`
+	DurationSeconds int                    `json:"durationSeconds"`
+	Failed          int                    `json:"failed,omitempty"`
+}
+
+// ParseLogFormat checks that the given string is a supported log format.
+func ParseLogFormat(format string) (LogFormat, error) {
+	switch LogFormat(format) {
+	case LogFormatText, LogFormatJSON:
+		return LogFormat(format), nil
+	default:
+		return "", &FieldError{
+			Field:   "logFormat",
+			Problem: fmt.Sprintf("unsupported format %q, use %q or %q", format, LogFormatText, LogFormatJSON),
+		}
+	}
+}
+
+// NewLogger returns a logger for the manager that writes to out in the given format.
+// With LogFormatJSON every line is written as an Event and the prefix is ignored.
+func NewLogger(out io.Writer, format LogFormat, prefix string) *log.Logger {
+	if format == LogFormatJSON {
+		return log.New(&jsonLogWriter{out: out}, "", 0)
+	}
+
+	return log.New(out, prefix, 0)
+}
+
+// jsonLogWriter wraps every log line in an Event.
+type jsonLogWriter struct {
+	out     io.Writer
+	cluster string
+}
+
+func (w *jsonLogWriter) Write(p []byte) (int, error) {
+	event := Event{Type: EventLog, Cluster: w.cluster, Message: strings.TrimSpace(string(p))}
+	if err := writeEvent(w.out, event); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func writeEvent(out io.Writer, event Event) error {
+	event.Time = time.Now().UTC()
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	// a single write keeps the lines intact if multiple clusters write at the same time
+	_, err = out.Write(append(line, '\n'))
+
+	return err
+}
+
+// eventWriter returns the writer for events if the manager logs in JSON format.
+func (m *Manager) eventWriter() (io.Writer, bool) {
+	w, ok := m.Logger.Writer().(*jsonLogWriter)
+	if !ok {
+		return nil, false
+	}
+
+	return w.out, true
+}
+
+// emit writes the event for the current cluster. It reports false if the manager doesn't log in JSON format.
+func (m *Manager) emit(event Event) bool {
+	out, ok := m.eventWriter()
+	if !ok {
+		return false
+	}
+
+	event.Cluster = m.Options.Name
+	if err := writeEvent(out, event); err != nil {
+		m.Logger.Printf("Failed to write event: %v\n", err)
+	}
+
+	return true
+}
+
+// LogStage reports that the given stage of the current operation was started.
+func (m *Manager) LogStage(stage, message string) {
+	if m.emit(Event{Type: EventStage, Stage: stage, Message: message}) {
+		return
+	}
+
+	m.Logger.Println(message)
+}
@@ -0,0 +1,84 @@
+package kindacool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/brumhard/kindacool/pkg/k3s"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ExecResult is the outcome of running a command on a single node.
+type ExecResult struct {
+	Node k3s.Node
+	// ExitCode is the exit status of the command or -1 if it couldn't be started.
+	ExitCode int
+	Err      error
+}
+
+// Exec runs the command on all given nodes at the same time.
+// The output of every node is written to the writers returned by output.
+// The results are returned in the same order as the nodes.
+func (a *NodeAccess) Exec(
+	ctx context.Context, nodes []k3s.Node, command string, output func(node k3s.Node) (stdout, stderr io.Writer),
+) []ExecResult {
+	results := make([]ExecResult, len(nodes))
+
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node k3s.Node) {
+			defer wg.Done()
+			stdout, stderr := output(node)
+			exitCode, err := a.exec(ctx, node, command, stdout, stderr)
+			results[i] = ExecResult{Node: node, ExitCode: exitCode, Err: err}
+		}(i, node)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (a *NodeAccess) exec(ctx context.Context, node k3s.Node, command string, stdout, stderr io.Writer) (int, error) {
+	client, err := a.Dial(ctx, node)
+	if err != nil {
+		return -1, err
+	}
+	defer client.Close()
+
+	// stop the command when the context is canceled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			client.Close()
+		case <-done:
+		}
+	}()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return -1, err
+	}
+	defer session.Close()
+
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	err = session.Run(command)
+
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus(), err
+	}
+
+	if err != nil {
+		return -1, err
+	}
+
+	return 0, nil
+}
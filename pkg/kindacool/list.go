@@ -0,0 +1,178 @@
+package kindacool
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/brumhard/kindacool/pkg/k3s"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// listParallelism is the number of stacks that are read at the same time by Manager.List.
+const listParallelism = 8
+
+const (
+	StatusSucceeded  = "succeeded"
+	StatusFailed     = "failed"
+	StatusInProgress = "in-progress"
+)
+
+// ClusterSummary is the short overview of a cluster as shown by Manager.List.
+type ClusterSummary struct {
+	Name          string            `json:"name"`
+	NodeCount     int               `json:"nodeCount,omitempty"`
+	Public        bool              `json:"public"`
+	K3sVersion    string            `json:"k3sVersion,omitempty"`
+	LastUpdate    string            `json:"lastUpdate,omitempty"`
+	Status        string            `json:"status,omitempty"`
+	ResourceCount int               `json:"resourceCount"`
+	Owner         string            `json:"owner,omitempty"`
+	Tags          map[string]string `json:"tags,omitempty"`
+}
+
+// List returns a summary of all clusters that match the given selector.
+// The stacks are read in parallel, at most listParallelism at the same time.
+func (m *Manager) List(ctx context.Context, selector Selector) ([]ClusterSummary, error) {
+	w, err := m.newWorkspace(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stacks, err := w.ListStacks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]*ClusterSummary, len(stacks))
+	errs := make([]error, len(stacks))
+	semaphore := make(chan struct{}, listParallelism)
+	// selecting a stack changes the workspace, the other commands pass the stack explicitly
+	var selectMu sync.Mutex
+
+	var wg sync.WaitGroup
+	for i, stack := range stacks {
+		wg.Add(1)
+		go func(i int, stack auto.StackSummary) {
+			defer wg.Done()
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+
+			summaries[i], errs[i] = m.summarize(ctx, w, stack, selector, &selectMu)
+		}(i, stack)
+	}
+
+	wg.Wait()
+
+	clusters := make([]ClusterSummary, 0, len(stacks))
+	for i, summary := range summaries {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+
+		if summary != nil {
+			clusters = append(clusters, *summary)
+		}
+	}
+
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Name < clusters[j].Name })
+
+	return clusters, nil
+}
+
+// ClusterNames returns the sorted names of all clusters without reading their details.
+func (m *Manager) ClusterNames(ctx context.Context) ([]string, error) {
+	w, err := m.newWorkspace(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stacks, err := w.ListStacks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(stacks))
+	for _, stack := range stacks {
+		names = append(names, stack.Name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// summarize collects the details for a single stack.
+// It returns nil if the stack's tags don't match the selector, without reading its outputs and history.
+// Since the summary is only informational, outputs, tags or history that can't be read
+// are logged and left empty instead of failing the whole list.
+func (m *Manager) summarize(
+	ctx context.Context, w auto.Workspace, stackSummary auto.StackSummary, selector Selector, selectMu *sync.Mutex,
+) (*ClusterSummary, error) {
+	summary := &ClusterSummary{
+		Name:       stackSummary.Name,
+		LastUpdate: stackSummary.LastUpdate,
+	}
+
+	if stackSummary.ResourceCount != nil {
+		summary.ResourceCount = *stackSummary.ResourceCount
+	}
+
+	tags, err := w.ListTags(ctx, stackSummary.Name)
+	if err != nil {
+		m.Logger.Printf("Failed to read the tags of cluster %q: %v\n", stackSummary.Name, err)
+	}
+
+	if !selector.Matches(tags) {
+		return nil, nil
+	}
+
+	summary.Tags = tags
+	summary.Owner = tags[TagOwner]
+
+	outputs, err := w.StackOutputs(ctx, stackSummary.Name)
+	if err != nil {
+		m.Logger.Printf("Failed to read the outputs of cluster %q: %v\n", stackSummary.Name, err)
+	}
+
+	if argsJSON, ok := outputs[OutputClusterArgs].Value.(string); ok {
+		args := &k3s.ClusterArgs{}
+		if err := json.Unmarshal([]byte(argsJSON), args); err == nil {
+			summary.NodeCount = args.NodeCount
+			summary.Public = args.Public
+		}
+	}
+
+	summary.K3sVersion, _ = outputs[OutputK3sVersion].Value.(string)
+
+	if stackSummary.UpdateInProgress {
+		summary.Status = StatusInProgress
+		return summary, nil
+	}
+
+	selectMu.Lock()
+	stack, err := auto.SelectStack(ctx, stackSummary.Name, w)
+	selectMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := stack.History(ctx, 1, 1)
+	if err != nil {
+		m.Logger.Printf("Failed to read the history of cluster %q: %v\n", stackSummary.Name, err)
+	}
+
+	if len(history) > 0 {
+		summary.Status = history[0].Result
+	}
+
+	return summary, nil
+}
@@ -2,19 +2,20 @@ package kindacool
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
-	"os/exec"
+	"time"
 
 	"github.com/brumhard/kindacool/pkg/k3s"
-	"github.com/gophercloud/gophercloud/openstack"
 
 	"github.com/pulumi/pulumi/pkg/v3/backend/httpstate"
 	pkgWorkspace "github.com/pulumi/pulumi/pkg/v3/workspace"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optdestroy"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
@@ -23,89 +24,209 @@ import (
 
 // TODO: make configurable
 const (
-	defaultProjectName = "kindacool"
-	OutputKubeconfig   = "kubeconfig"
-	OutputSSHKey       = "sshKey"
+	defaultProjectName       = "kindacool"
+	OutputKubeconfig         = "kubeconfig"
+	OutputSSHKey             = "sshKey"
+	OutputClusterArgs        = "clusterArgs"
+	OutputK3sVersion         = "k3sVersion"
+	OutputNodes              = "nodes"
+	OutputNetworkIDs         = "networkIDs"
+	OutputSecurityGroupRules = "securityGroupRules"
 )
 
 var (
-	ErrPulumiNotInPath   = errors.New("pulumi executable not found in $PATH")
-	ErrUnauthorized      = errors.New(".openrc env vars for openstack could not be found")
+	ErrPulumiNotInPath   = errors.New("pulumi executable not found in $PATH and the managed pulumi CLI is not available")
+	ErrUnauthorized      = errors.New("openstack credentials not found, use an .openrc file, a clouds.yaml entry or an application credential")
 	ErrOutputUnavailable = errors.New("output could not be found")
+	ErrNoBackend         = errors.New("pulumi backend is not available, run 'pulumi login'")
+	ErrStackLocked       = errors.New("another operation is in progress for the cluster")
+	ErrRefreshFailed     = errors.New("failed to refresh stack")
+	ErrUpdateFailed      = errors.New("failed to update stack")
+	ErrDestroyFailed     = errors.New("failed to destroy stack")
 )
 
+// TagOwner is the stack tag that stores the user that created the cluster.
+const TagOwner = "owner"
+
+// RunOptions contains the settings for Manager.Run that are not part of the cluster itself.
+type RunOptions struct {
+	// Tags are added to the cluster's stack and can be used to select clusters.
+	Tags map[string]string
+	// SkipPreflight disables the checks of the OpenStack resources and quotas before the resources are created.
+	SkipPreflight bool
+	// Retries is the number of times the deployment is retried if it failed because of transient errors,
+	// e.g. an unavailable OpenStack API or a node that can't be reached via SSH yet.
+	Retries int
+	// WaitTimeout is the time to wait for the cluster to become ready after the resources are created.
+	// The readiness isn't checked if it's 0.
+	WaitTimeout time.Duration
+}
+
 type Manager struct {
 	Options GlobalOptions
 	Logger  *log.Logger
+
+	// pulumi is the CLI that is used by the automation API, it is set by EnsureEnvironment.
+	// If it's nil, the pulumi CLI in $PATH is used.
+	pulumi auto.PulumiCommand
 }
 
-func EnsureEnvironment(ctx context.Context) error {
-	pul := exec.CommandContext(ctx, "pulumi", "--help")
-	if err := pul.Run(); err != nil {
-		return ErrPulumiNotInPath
+// EnsureEnvironment checks that pulumi is installed and logged in and that credentials for the cloud are available.
+// If there's no pulumi CLI in $PATH, the managed pulumi CLI is installed.
+func (m *Manager) EnsureEnvironment(ctx context.Context) error {
+	pulumiCommand, err := ensurePulumi(ctx, m.Options.Pulumi, m.Logger)
+	if err != nil {
+		return err
 	}
+	m.pulumi = pulumiCommand
 
-	_, err := httpstate.NewLoginManager().Current(
+	_, err = httpstate.NewLoginManager().Current(
 		ctx,
 		httpstate.DefaultURL(pkgWorkspace.Instance),
 		false, false,
 	)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrNoBackend, err)
+	}
+
+	cloud, err := m.Options.Cloud.resolve()
 	if err != nil {
 		return err
 	}
 
-	_, err = openstack.AuthOptionsFromEnv()
+	_, err = cloud.credentials()
+
+	return err
+}
+
+// newWorkspace returns a workspace for the kindacool project
+// that can be used to access the already existing stacks.
+func (m *Manager) newWorkspace(ctx context.Context) (auto.Workspace, error) {
+	project := workspace.Project{
+		Name:    tokens.PackageName(defaultProjectName),
+		Runtime: workspace.NewProjectRuntimeInfo("go", nil),
+	}
+
+	return auto.NewLocalWorkspace(ctx, append(m.workspaceOptions(), auto.Project(project))...)
+}
+
+// workspaceOptions returns the options that all workspaces of the manager are created with.
+func (m *Manager) workspaceOptions() []auto.LocalWorkspaceOption {
+	if m.pulumi == nil {
+		return nil
+	}
+
+	return []auto.LocalWorkspaceOption{auto.Pulumi(m.pulumi)}
+}
+
+// selectStack returns the stack of the current cluster.
+func (m *Manager) selectStack(ctx context.Context) (auto.Stack, error) {
+	w, err := m.newWorkspace(ctx)
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrUnauthorized, err)
+		return auto.Stack{}, err
 	}
 
-	return nil
+	return auto.SelectStack(ctx, m.Options.Name, w)
 }
 
-func (m *Manager) Run(ctx context.Context, args *k3s.ClusterArgs) error {
-	// inline pulumi program
-	deployFunc := func(ctx *pulumi.Context) error {
-		cluster, err := k3s.NewCluster(ctx, m.Options.Name, args)
+// program returns the inline pulumi program that creates the cluster with the given args
+// and the OpenStack provider of the given function.
+// The options and args are validated first, so that invalid settings are reported before any pulumi call.
+func (m *Manager) program(args *k3s.ClusterArgs, providerFor providerFunc) (pulumi.RunFunc, error) {
+	if err := errors.Join(
+		m.Options.Validate(), m.Options.ValidateName(), m.Options.validateNewName(), args.Validate(),
+	); err != nil {
+		return nil, err
+	}
+
+	argsJSON, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// the program requests the same plugin versions that are installed by EnsurePlugins
+	versions, err := m.Options.Plugins.pluginVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx *pulumi.Context) error {
+		provider, err := providerFor(ctx, m.Options.Name, versions["openstack"])
+		if err != nil {
+			return err
+		}
+
+		cluster, err := k3s.NewCluster(ctx, m.Options.Name, args, k3s.Providers{
+			OpenStack:      provider,
+			CommandVersion: versions["command"],
+		})
 		if err != nil {
 			return err
 		}
 
 		ctx.Export(OutputKubeconfig, pulumi.ToSecret(cluster.Kubeconfig))
 		ctx.Export(OutputSSHKey, pulumi.ToSecret(cluster.SSHKey))
+		ctx.Export(OutputClusterArgs, pulumi.String(argsJSON))
+		ctx.Export(OutputK3sVersion, cluster.K3sVersion)
+		ctx.Export(OutputNodes, cluster.Nodes)
+		ctx.Export(OutputNetworkIDs, cluster.NetworkIDs)
+		ctx.Export(OutputSecurityGroupRules, cluster.SecurityGroupRules)
 
 		return nil
+	}, nil
+}
+
+func (m *Manager) Run(ctx context.Context, args *k3s.ClusterArgs, opts RunOptions) error {
+	// inline pulumi program
+	deployFunc, err := m.program(args, providerFromConfig)
+	if err != nil {
+		return err
+	}
+
+	cloud, err := m.clusterCloud(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !opts.SkipPreflight {
+		m.LogStage(StagePreflight, "Checking OpenStack resources and quotas")
+		if err := m.preflight(ctx, cloud, args); err != nil {
+			return err
+		}
 	}
 
 	stackName := m.Options.Name
 
-	m.Logger.Printf("Creating/using stack %q\n", stackName)
+	m.LogStage(StageStack, fmt.Sprintf("Creating/using stack %q", stackName))
 	// TODO(brumhard): probably can add auto.Project() and then configure a custom backend there
 	// to not require the user to login and just use file backend in some ~/.kindacool dir maybe.
-	s, err := auto.UpsertStackInlineSource(ctx, stackName, defaultProjectName, deployFunc)
+	s, err := auto.UpsertStackInlineSource(ctx, stackName, defaultProjectName, deployFunc, m.workspaceOptions()...)
 	if err != nil {
 		return fmt.Errorf("failed to get/create stack: %w", err)
 	}
 
-	m.Logger.Println("Installing required pulumi plugins")
-	if err := EnsurePlugins(ctx, s.Workspace()); err != nil {
+	if err := pinCloud(ctx, s, cloud); err != nil {
 		return err
 	}
 
-	m.Logger.Println("Checking for existing resources")
-	_, err = s.Refresh(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to refresh stack: %w", err)
+	if err := m.setTags(ctx, s, opts.Tags); err != nil {
+		return err
 	}
 
-	m.Logger.Println("Creating/updating required resources")
-	stdoutStreamer := optup.ProgressStreams(io.Discard)
-	if m.Options.Verbose {
-		stdoutStreamer = optup.ProgressStreams(m.Logger.Writer())
+	m.LogStage(StagePlugins, "Installing required pulumi plugins")
+	if err := EnsurePlugins(ctx, s.Workspace(), m.Options.Plugins); err != nil {
+		return err
 	}
 
-	_, err = s.Up(ctx, stdoutStreamer)
-	if err != nil {
-		return fmt.Errorf("failed to update stack: %w", err)
+	if err := m.retry(ctx, opts.Retries, func() error { return m.deploy(ctx, s) }); err != nil {
+		return err
+	}
+
+	if opts.WaitTimeout > 0 {
+		m.LogStage(StageWait, "Waiting for the nodes and core workloads to become ready")
+		if _, err := m.WaitReady(ctx, nil, opts.WaitTimeout); err != nil {
+			return err
+		}
 	}
 
 	m.Logger.Println("Successfully created your fresh k3s cluster!")
@@ -113,18 +234,68 @@ func (m *Manager) Run(ctx context.Context, args *k3s.ClusterArgs) error {
 	return nil
 }
 
-func (m *Manager) Destroy(ctx context.Context) error {
-	project := workspace.Project{
-		Name:    tokens.PackageName(defaultProjectName),
-		Runtime: workspace.NewProjectRuntimeInfo("go", nil),
+// deploy refreshes the stack and creates or updates all resources.
+func (m *Manager) deploy(ctx context.Context, s auto.Stack) error {
+	m.LogStage(StageRefresh, "Checking for existing resources")
+	// the refresh is not rendered, its events are only used to report the failed resources
+	eventStream, waitForProgress := m.trackProgress(false)
+	_, err := s.Refresh(ctx, optrefresh.ProgressStreams(io.Discard), optrefresh.EventStreams(eventStream))
+	if failures := waitForProgress(err); err != nil {
+		return operationError(ErrRefreshFailed, err, failures)
+	}
+
+	m.LogStage(StageUp, "Creating/updating required resources")
+	eventStream, waitForProgress, showPulumiOutput := m.progressStreams()
+	upOpts := []optup.Option{optup.ProgressStreams(io.Discard), optup.EventStreams(eventStream)}
+	if showPulumiOutput {
+		upOpts[0] = optup.ProgressStreams(m.Logger.Writer())
+	}
+
+	_, err = s.Up(ctx, upOpts...)
+	if failures := waitForProgress(err); err != nil {
+		return operationError(ErrUpdateFailed, err, failures)
 	}
 
-	w, err := auto.NewLocalWorkspace(ctx, auto.Project(project))
+	return nil
+}
+
+// setTags adds the given tags to the stack. The owner is only set if the stack doesn't have one yet.
+// Not all backends support tags, in that case a warning is logged instead of failing.
+func (m *Manager) setTags(ctx context.Context, s auto.Stack, tags map[string]string) error {
+	existingTags, err := s.ListTags(ctx)
+	if err != nil {
+		m.Logger.Printf("Could not read tags, the backend might not support tags: %v\n", err)
+		return nil
+	}
+
+	allTags := map[string]string{}
+	if _, ok := existingTags[TagOwner]; !ok {
+		if owner, err := s.Workspace().WhoAmI(ctx); err == nil {
+			allTags[TagOwner] = owner
+		}
+	}
+
+	for key, value := range tags {
+		allTags[key] = value
+	}
+
+	for key, value := range allTags {
+		if err := s.SetTag(ctx, key, value); err != nil {
+			m.Logger.Printf("Could not set tag %q, the backend might not support tags: %v\n", key, err)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) Destroy(ctx context.Context) error {
+	w, err := m.newWorkspace(ctx)
 	if err != nil {
 		return err
 	}
 
-	m.Logger.Println("Looking for cluster")
+	m.LogStage(StageStack, "Looking for cluster")
 	stack, err := auto.SelectStack(ctx, m.Options.Name, w)
 	if err != nil {
 		if auto.IsSelectStack404Error(err) {
@@ -135,68 +306,44 @@ func (m *Manager) Destroy(ctx context.Context) error {
 		return err
 	}
 
-	m.Logger.Println("Installing required pulumi plugins")
-	if err := EnsurePlugins(ctx, w); err != nil {
+	cloud, err := m.clusterCloud(ctx)
+	if err != nil {
 		return err
 	}
 
-	m.Logger.Println("Destroying resources")
-	stdoutStreamer := optdestroy.ProgressStreams(io.Discard)
-	if m.Options.Verbose {
-		stdoutStreamer = optdestroy.ProgressStreams(m.Logger.Writer())
+	if err := pinCloud(ctx, stack, cloud); err != nil {
+		return err
 	}
 
-	_, err = stack.Destroy(ctx, stdoutStreamer)
-	if err != nil {
+	m.LogStage(StagePlugins, "Installing required pulumi plugins")
+	if err := EnsurePlugins(ctx, w, m.Options.Plugins); err != nil {
 		return err
 	}
 
-	m.Logger.Println("Removing stack")
-	w.RemoveStack(ctx, m.Options.Name)
-
-	m.Logger.Println("Your cluster was destroyed successfully!")
-
-	return nil
-}
-
-func (m *Manager) List(ctx context.Context) ([]string, error) {
-	project := workspace.Project{
-		Name:    tokens.PackageName(defaultProjectName),
-		Runtime: workspace.NewProjectRuntimeInfo("go", nil),
+	m.LogStage(StageDestroy, "Destroying resources")
+	eventStream, waitForProgress, showPulumiOutput := m.progressStreams()
+	destroyOpts := []optdestroy.Option{optdestroy.ProgressStreams(io.Discard), optdestroy.EventStreams(eventStream)}
+	if showPulumiOutput {
+		destroyOpts[0] = optdestroy.ProgressStreams(m.Logger.Writer())
 	}
 
-	w, err := auto.NewLocalWorkspace(ctx, auto.Project(project))
-	if err != nil {
-		return nil, err
+	_, err = stack.Destroy(ctx, destroyOpts...)
+	if failures := waitForProgress(err); err != nil {
+		return operationError(ErrDestroyFailed, err, failures)
 	}
 
-	stacks, err := w.ListStacks(ctx)
-	if err != nil {
-		return nil, err
-	}
+	m.Logger.Println("Removing stack")
+	w.RemoveStack(ctx, m.Options.Name)
 
-	clusters := make([]string, 0, len(stacks))
-	for _, stack := range stacks {
-		clusters = append(clusters, stack.Name)
-	}
+	m.Logger.Println("Your cluster was destroyed successfully!")
 
-	return clusters, nil
+	return nil
 }
 
 // FetchOutput gets an output from the current stack.
 // The available outputs are defined as consts.
 func (m *Manager) FetchOutput(ctx context.Context, outputKey string) (string, error) {
-	project := workspace.Project{
-		Name:    tokens.PackageName(defaultProjectName),
-		Runtime: workspace.NewProjectRuntimeInfo("go", nil),
-	}
-
-	w, err := auto.NewLocalWorkspace(ctx, auto.Project(project))
-	if err != nil {
-		return "", err
-	}
-
-	stack, err := auto.SelectStack(ctx, m.Options.Name, w)
+	stack, err := m.selectStack(ctx)
 	if err != nil {
 		return "", err
 	}
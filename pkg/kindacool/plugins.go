@@ -2,21 +2,204 @@ package kindacool
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
 
+	"github.com/blang/semver"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
 )
 
-func EnsurePlugins(ctx context.Context, w auto.Workspace) error {
-	// for inline source programs, we must manage plugins ourselves
-	err := w.InstallPlugin(ctx, "openstack", "v3.9.0")
+var (
+	ErrPluginInstall = errors.New("failed to install pulumi plugin")
+	ErrPluginVersion = errors.New("the plugin version could not be determined from the build info")
+)
+
+// Plugin is a pulumi resource plugin that is required by the cluster program.
+type Plugin struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// module is the Go SDK of the plugin that determines the default version.
+	module string
+}
+
+// PluginOptions configures the versions and the source of the pulumi plugins.
+type PluginOptions struct {
+	// Versions overrides the version of plugins by their name.
+	// The versions are used by the cluster program as well, so the SDKs' versions are never requested.
+	Versions map[string]string `json:"versions,omitempty"`
+	// Source is a local directory or the URL of a plugin server to install the plugins from.
+	// By default the plugins are downloaded from pulumi's servers.
+	// A directory must contain the release archives like pulumi-resource-openstack-v3.15.2-linux-amd64.tar.gz.
+	Source string `json:"source,omitempty"`
+}
+
+// requiredPlugins are the plugins that are used by the cluster program.
+var requiredPlugins = []Plugin{
+	{Name: "openstack", module: "github.com/pulumi/pulumi-openstack/sdk/v3"},
+	{Name: "command", module: "github.com/pulumi/pulumi-command/sdk"},
+}
+
+// RequiredPlugins returns the plugins that are installed by EnsurePlugins with their default versions.
+// The versions match the plugins' Go SDKs the program is compiled with.
+// It fails if the versions can't be read from the build info.
+func RequiredPlugins() ([]Plugin, error) {
+	return PluginOptions{}.plugins()
+}
+
+// plugins returns the required plugins with the versions of the options applied.
+func (o PluginOptions) plugins() ([]Plugin, error) {
+	modules := map[string]string{}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			modules[dep.Path] = dep.Version
+		}
+	}
+
+	plugins := make([]Plugin, len(requiredPlugins))
+	copy(plugins, requiredPlugins)
+
+	var errs []error
+	for i, plugin := range plugins {
+		if version, ok := o.Versions[plugin.Name]; ok {
+			plugins[i].Version = "v" + strings.TrimPrefix(version, "v")
+			continue
+		}
+
+		sdkVersion, ok := modules[plugin.module]
+		if !ok {
+			errs = append(errs, fmt.Errorf(
+				"%w: %s is missing, set --plugin-version %s=<version>", ErrPluginVersion, plugin.module, plugin.Name,
+			))
+			continue
+		}
+
+		// pseudo versions of unreleased SDKs have no matching plugin release
+		version, err := semver.Parse(strings.TrimPrefix(sdkVersion, "v"))
+		if err != nil || len(version.Pre) > 0 {
+			errs = append(errs, fmt.Errorf(
+				"%w: %s has no released version (%s), set --plugin-version %s=<version>",
+				ErrPluginVersion, plugin.module, sdkVersion, plugin.Name,
+			))
+			continue
+		}
+
+		plugins[i].Version = "v" + version.String()
+	}
+
+	return plugins, errors.Join(errs...)
+}
+
+// pluginVersions returns the versions of the required plugins by name as expected by pulumi.Version.
+func (o PluginOptions) pluginVersions() (map[string]string, error) {
+	plugins, err := o.plugins()
 	if err != nil {
-		return fmt.Errorf("failed to install openstack plugin: %w", err)
+		return nil, err
+	}
+
+	versions := make(map[string]string, len(plugins))
+	for _, plugin := range plugins {
+		versions[plugin.Name] = strings.TrimPrefix(plugin.Version, "v")
+	}
+
+	return versions, nil
+}
+
+// validate checks that the overridden versions belong to required plugins and are valid versions.
+func (o PluginOptions) validate() []error {
+	known := map[string]bool{}
+	for _, plugin := range requiredPlugins {
+		known[plugin.Name] = true
+	}
+
+	names := make([]string, 0, len(o.Versions))
+	for name := range o.Versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		version := o.Versions[name]
+		if !known[name] {
+			errs = append(errs, &FieldError{Field: "pluginVersion", Problem: fmt.Sprintf("%q is not a required plugin", name)})
+			continue
+		}
+
+		if _, err := semver.Parse(strings.TrimPrefix(version, "v")); err != nil {
+			errs = append(errs, &FieldError{Field: "pluginVersion", Problem: fmt.Sprintf("%q of %s is invalid: %v", version, name, err)})
+		}
+	}
+
+	return errs
+}
+
+// EnsurePlugins installs the required plugins that are not installed yet.
+func EnsurePlugins(ctx context.Context, w auto.Workspace, opts PluginOptions) error {
+	plugins, err := opts.plugins()
+	if err != nil {
+		return err
+	}
+
+	installed, err := w.ListPlugins(ctx)
+	if err != nil {
+		return err
+	}
+
+	isInstalled := map[string]bool{}
+	for _, plugin := range installed {
+		if plugin.Kind == apitype.ResourcePlugin && plugin.Version != nil {
+			isInstalled[plugin.Name+"@v"+plugin.Version.String()] = true
+		}
+	}
+
+	// for inline source programs, we must manage plugins ourselves
+	for _, plugin := range plugins {
+		if isInstalled[plugin.Name+"@"+plugin.Version] {
+			continue
+		}
+
+		if err := opts.install(ctx, w, plugin); err != nil {
+			return fmt.Errorf("%w %s %s: %w", ErrPluginInstall, plugin.Name, plugin.Version, err)
+		}
+	}
+
+	return nil
+}
+
+// install installs the plugin from the configured source.
+func (o PluginOptions) install(ctx context.Context, w auto.Workspace, plugin Plugin) error {
+	switch {
+	case o.Source == "":
+		return w.InstallPlugin(ctx, plugin.Name, plugin.Version)
+	case strings.Contains(o.Source, "://"):
+		return w.InstallPluginFromServer(ctx, plugin.Name, plugin.Version, o.Source)
+	}
+
+	archive := filepath.Join(o.Source, fmt.Sprintf(
+		"pulumi-resource-%s-%s-%s-%s.tar.gz", plugin.Name, plugin.Version, runtime.GOOS, runtime.GOARCH,
+	))
+	if _, err := os.Stat(archive); err != nil {
+		return err
+	}
+
+	env := make([]string, 0, len(w.GetEnvVars()))
+	for key, value := range w.GetEnvVars() {
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
 	}
 
-	err = w.InstallPlugin(ctx, "command", "v0.7.0")
+	_, stderr, _, err := w.PulumiCommand().Run(
+		ctx, w.WorkDir(), nil, nil, nil, env,
+		"plugin", "install", "resource", plugin.Name, plugin.Version, "--file", archive,
+	)
 	if err != nil {
-		return fmt.Errorf("failed to install command plugin: %w", err)
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr))
 	}
 
 	return nil
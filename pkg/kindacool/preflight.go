@@ -0,0 +1,411 @@
+package kindacool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/brumhard/kindacool/pkg/k3s"
+	"github.com/gophercloud/gophercloud/openstack"
+	volumelimits "github.com/gophercloud/gophercloud/openstack/blockstorage/extensions/limits"
+	computelimits "github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/limits"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/quotas"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+// resources that are limited by quotas in OpenStack.
+const (
+	quotaInstances          = "instances"
+	quotaCores              = "cores"
+	quotaRAM                = "RAM (MB)"
+	quotaVolumes            = "volumes"
+	quotaGigabytes          = "volume gigabytes"
+	quotaFloatingIPs        = "floating IPs"
+	quotaNetworks           = "networks"
+	quotaSubnets            = "subnets"
+	quotaRouters            = "routers"
+	quotaPorts              = "ports"
+	quotaSecurityGroups     = "security groups"
+	quotaSecurityGroupRules = "security group rules"
+)
+
+// defaultEgressRules is the amount of rules OpenStack adds to every new security group.
+const defaultEgressRules = 2
+
+// instanceType is the type of the cluster's nodes in the stack state.
+const instanceType = "openstack:compute/instance:Instance"
+
+// stateResourceUsage is the usage of the resource types in the stack state that doesn't depend on their properties.
+var stateResourceUsage = map[string]resourceUsage{
+	instanceType: {quotaInstances: 1, quotaPorts: 1},
+	"openstack:networking/floatingIp:FloatingIp":           {quotaFloatingIPs: 1},
+	"openstack:networking/network:Network":                 {quotaNetworks: 1},
+	"openstack:networking/subnet:Subnet":                   {quotaSubnets: 1},
+	"openstack:networking/router:Router":                   {quotaRouters: 1},
+	"openstack:networking/routerInterface:RouterInterface": {quotaPorts: 1},
+	"openstack:networking/secGroup:SecGroup":               {quotaSecurityGroups: 1, quotaSecurityGroupRules: defaultEgressRules},
+	"openstack:networking/secGroupRule:SecGroupRule":       {quotaSecurityGroupRules: 1},
+}
+
+var ErrPreflightFailed = errors.New("preflight checks failed")
+
+// PreflightError contains all problems that were found before creating the cluster's resources.
+type PreflightError struct {
+	Problems []string
+}
+
+func (e *PreflightError) Error() string {
+	return fmt.Sprintf("%s:\n  - %s", ErrPreflightFailed, strings.Join(e.Problems, "\n  - "))
+}
+
+func (e *PreflightError) Unwrap() error {
+	return ErrPreflightFailed
+}
+
+// quota is the limit of a resource and how much of it is already used. A negative limit means unlimited.
+type quota struct {
+	limit int
+	used  int
+}
+
+// resourceUsage is the amount of resources per quota.
+type resourceUsage map[string]int
+
+// preflight checks that the resources that are referenced by the args exist
+// and that the project's quotas are sufficient for the cluster.
+// If the cluster already exists, only the additional resources have to fit into the quotas.
+func (m *Manager) preflight(ctx context.Context, cloud *Cloud, args *k3s.ClusterArgs) error {
+	flavor, problems, err := cloud.checkReferences(args)
+	if err != nil {
+		return err
+	}
+
+	deployed, err := m.deployedUsage(ctx, cloud, args, flavor)
+	if err != nil {
+		return err
+	}
+
+	required := clusterUsage(args, flavor)
+	for resource, amount := range deployed {
+		required[resource] -= amount
+	}
+
+	for _, fetch := range []struct {
+		service string
+		quotas  func() (map[string]quota, error)
+	}{
+		{"compute", cloud.computeQuotas},
+		{"network", cloud.networkQuotas},
+		{"volume", cloud.volumeQuotas},
+	} {
+		quotas, err := fetch.quotas()
+		if err != nil {
+			m.Logger.Printf("Skipping the %s quota checks: %v\n", fetch.service, err)
+			continue
+		}
+
+		problems = append(problems, checkQuotas(quotas, required)...)
+	}
+
+	if len(problems) > 0 {
+		return &PreflightError{Problems: problems}
+	}
+
+	return nil
+}
+
+// deployedUsage returns the resources that already exist for the cluster and are included in the quotas' usage.
+// It's empty if the cluster doesn't exist yet.
+func (m *Manager) deployedUsage(
+	ctx context.Context, cloud *Cloud, args *k3s.ClusterArgs, flavor *flavors.Flavor,
+) (resourceUsage, error) {
+	description, err := m.Describe(ctx)
+	if err != nil {
+		if auto.IsSelectStack404Error(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	if previousArgs := description.Args; previousArgs != nil {
+		previousFlavor := flavor
+		if previousArgs.MachineFlavor != args.MachineFlavor {
+			if previousFlavor, err = cloud.flavor(previousArgs.MachineFlavor); err != nil {
+				return nil, err
+			}
+		}
+
+		return clusterUsage(previousArgs, previousFlavor), nil
+	}
+
+	// the args are only exported by a successful update, the resources of a failed create are counted from the state
+	stack, err := m.selectStack(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := stack.Export(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export the stack state: %w", err)
+	}
+
+	return stateUsage(state, cloud.flavor)
+}
+
+// stateUsage returns the resources in the stack's state per quota.
+// The flavors of the instances are looked up with the given function to count their cores and RAM.
+func stateUsage(state apitype.UntypedDeployment, flavor func(name string) (*flavors.Flavor, error)) (resourceUsage, error) {
+	usage := resourceUsage{}
+	if len(state.Deployment) == 0 {
+		return usage, nil
+	}
+
+	var deployment struct {
+		Resources []struct {
+			Type    string `json:"type"`
+			Outputs struct {
+				FlavorName   string `json:"flavorName"`
+				BlockDevices []struct {
+					DestinationType string `json:"destinationType"`
+					VolumeSize      int    `json:"volumeSize"`
+				} `json:"blockDevices"`
+			} `json:"outputs"`
+		} `json:"resources"`
+	}
+	if err := json.Unmarshal(state.Deployment, &deployment); err != nil {
+		return nil, fmt.Errorf("failed to decode the stack state: %w", err)
+	}
+
+	instanceFlavors := map[string]*flavors.Flavor{}
+	for _, resource := range deployment.Resources {
+		for resourceQuota, amount := range stateResourceUsage[resource.Type] {
+			usage[resourceQuota] += amount
+		}
+
+		if resource.Type != instanceType {
+			continue
+		}
+
+		name := resource.Outputs.FlavorName
+		if _, ok := instanceFlavors[name]; !ok {
+			found, err := flavor(name)
+			if err != nil {
+				return nil, err
+			}
+			instanceFlavors[name] = found
+		}
+
+		if found := instanceFlavors[name]; found != nil {
+			usage[quotaCores] += found.VCPUs
+			usage[quotaRAM] += found.RAM
+		}
+
+		for _, device := range resource.Outputs.BlockDevices {
+			if device.DestinationType == "volume" {
+				usage[quotaVolumes]++
+				usage[quotaGigabytes] += device.VolumeSize
+			}
+		}
+	}
+
+	return usage, nil
+}
+
+// checkReferences checks that the flavor, image and networks of the args exist.
+// The flavor is returned for the quota checks, it is nil if it doesn't exist.
+func (c *Cloud) checkReferences(args *k3s.ClusterArgs) (*flavors.Flavor, []string, error) {
+	var problems []string
+
+	flavor, err := c.flavor(args.MachineFlavor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if flavor == nil {
+		problems = append(problems, fmt.Sprintf("flavor %q not found", args.MachineFlavor))
+	}
+
+	images, err := c.Images()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !slices.Contains(images, args.MachineImage) {
+		problems = append(problems, fmt.Sprintf("image %q not found or not active", args.MachineImage))
+	}
+
+	networks, err := c.Networks()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return flavor, append(problems, checkNetworks(networks, args)...), nil
+}
+
+func checkNetworks(networks []Network, args *k3s.ClusterArgs) []string {
+	byName := map[string]Network{}
+	for _, network := range networks {
+		byName[network.Name] = network
+	}
+
+	if !args.Public {
+		if _, ok := byName[args.PrivateNetworkName]; !ok {
+			return []string{fmt.Sprintf("private network %q not found", args.PrivateNetworkName)}
+		}
+
+		return nil
+	}
+
+	var problems []string
+
+	publicNetwork, ok := byName[args.PublicNetworkName]
+	switch {
+	case !ok:
+		problems = append(problems, fmt.Sprintf("public network %q not found", args.PublicNetworkName))
+	case !publicNetwork.External:
+		problems = append(problems, fmt.Sprintf("public network %q is not an external network", args.PublicNetworkName))
+	case publicNetwork.ID != args.PublicNetworkID:
+		problems = append(problems, fmt.Sprintf(
+			"public network %q has the ID %q, not %q", args.PublicNetworkName, publicNetwork.ID, args.PublicNetworkID,
+		))
+	}
+
+	if pool, ok := byName[args.PublicIPPool]; !ok || !pool.External {
+		problems = append(problems, fmt.Sprintf("public IP pool %q not found", args.PublicIPPool))
+	}
+
+	return problems
+}
+
+// clusterUsage returns the resources that are created for a cluster with the given args.
+// Without a flavor the cores and RAM are unknown and not included.
+func clusterUsage(args *k3s.ClusterArgs, flavor *flavors.Flavor) resourceUsage {
+	usage := resourceUsage{
+		quotaInstances:          args.NodeCount,
+		quotaPorts:              args.NodeCount,
+		quotaSecurityGroups:     1,
+		quotaSecurityGroupRules: len(k3s.SecurityGroupPorts(args.AdditionalPorts)) + defaultEgressRules,
+	}
+
+	if flavor != nil {
+		usage[quotaCores] = args.NodeCount * flavor.VCPUs
+		usage[quotaRAM] = args.NodeCount * flavor.RAM
+	}
+
+	if args.VolumeSize > 0 {
+		usage[quotaVolumes] = args.NodeCount
+		usage[quotaGigabytes] = args.NodeCount * args.VolumeSize
+	}
+
+	if args.Public {
+		usage[quotaFloatingIPs] = args.NodeCount
+		usage[quotaNetworks] = 1
+		usage[quotaSubnets] = 1
+		usage[quotaRouters] = 1
+		// the router's interface in the subnet
+		usage[quotaPorts]++
+	}
+
+	return usage
+}
+
+// checkQuotas returns a problem for every resource that exceeds its quota.
+func checkQuotas(quotas map[string]quota, required resourceUsage) []string {
+	var problems []string
+	for resource, q := range quotas {
+		amount := required[resource]
+		if amount <= 0 || q.limit < 0 || q.used+amount <= q.limit {
+			continue
+		}
+
+		available := q.limit - q.used
+		if available < 0 {
+			available = 0
+		}
+
+		problems = append(problems, fmt.Sprintf(
+			"quota for %s exceeded: %d required, %d of %d available", resource, amount, available, q.limit,
+		))
+	}
+	sort.Strings(problems)
+
+	return problems
+}
+
+func (c *Cloud) computeQuotas() (map[string]quota, error) {
+	client, err := openstack.NewComputeV2(c.provider, c.endpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	limits, err := computelimits.Get(client, computelimits.GetOpts{}).Extract()
+	if err != nil {
+		return nil, err
+	}
+
+	absolute := limits.Absolute
+
+	return map[string]quota{
+		quotaInstances: {limit: absolute.MaxTotalInstances, used: absolute.TotalInstancesUsed},
+		quotaCores:     {limit: absolute.MaxTotalCores, used: absolute.TotalCoresUsed},
+		quotaRAM:       {limit: absolute.MaxTotalRAMSize, used: absolute.TotalRAMUsed},
+	}, nil
+}
+
+func (c *Cloud) volumeQuotas() (map[string]quota, error) {
+	client, err := openstack.NewBlockStorageV3(c.provider, c.endpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	limits, err := volumelimits.Get(client).Extract()
+	if err != nil {
+		return nil, err
+	}
+
+	absolute := limits.Absolute
+
+	return map[string]quota{
+		quotaVolumes:   {limit: absolute.MaxTotalVolumes, used: absolute.TotalVolumesUsed},
+		quotaGigabytes: {limit: absolute.MaxTotalVolumeGigabytes, used: absolute.TotalGigabytesUsed},
+	}, nil
+}
+
+func (c *Cloud) networkQuotas() (map[string]quota, error) {
+	client, err := openstack.NewNetworkV2(c.provider, c.endpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	projectID, err := c.projectID()
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := quotas.GetDetail(client, projectID).Extract()
+	if err != nil {
+		return nil, err
+	}
+
+	toQuota := func(detail quotas.QuotaDetail) quota {
+		return quota{limit: detail.Limit, used: detail.Used + detail.Reserved}
+	}
+
+	return map[string]quota{
+		quotaFloatingIPs:        toQuota(details.FloatingIP),
+		quotaNetworks:           toQuota(details.Network),
+		quotaSubnets:            toQuota(details.Subnet),
+		quotaRouters:            toQuota(details.Router),
+		quotaPorts:              toQuota(details.Port),
+		quotaSecurityGroups:     toQuota(details.SecurityGroup),
+		quotaSecurityGroupRules: toQuota(details.SecurityGroupRule),
+	}, nil
+}
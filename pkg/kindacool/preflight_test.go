@@ -0,0 +1,336 @@
+package kindacool
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/brumhard/kindacool/pkg/k3s"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func TestCheckQuotas(t *testing.T) {
+	tests := []struct {
+		name     string
+		quotas   map[string]quota
+		required resourceUsage
+		want     []string
+	}{
+		{
+			name:     "fits",
+			quotas:   map[string]quota{quotaInstances: {limit: 10, used: 7}},
+			required: resourceUsage{quotaInstances: 3},
+		},
+		{
+			name:     "unlimited",
+			quotas:   map[string]quota{quotaInstances: {limit: -1, used: 100}},
+			required: resourceUsage{quotaInstances: 3},
+		},
+		{
+			name:     "nothing required",
+			quotas:   map[string]quota{quotaRouters: {limit: 1, used: 1}},
+			required: resourceUsage{quotaRouters: 0},
+		},
+		{
+			name:     "fewer resources than deployed",
+			quotas:   map[string]quota{quotaInstances: {limit: 1, used: 3}},
+			required: resourceUsage{quotaInstances: -2},
+		},
+		{
+			name:     "not limited by a quota",
+			quotas:   map[string]quota{},
+			required: resourceUsage{quotaInstances: 3},
+		},
+		{
+			name: "exceeded",
+			quotas: map[string]quota{
+				quotaInstances: {limit: 10, used: 8},
+				quotaCores:     {limit: 20, used: 4},
+			},
+			required: resourceUsage{quotaInstances: 3, quotaCores: 6},
+			want:     []string{"quota for instances exceeded: 3 required, 2 of 10 available"},
+		},
+		{
+			name: "already over the limit",
+			quotas: map[string]quota{
+				quotaFloatingIPs: {limit: 2, used: 3},
+				quotaRAM:         {limit: 8192, used: 8192},
+			},
+			required: resourceUsage{quotaFloatingIPs: 1, quotaRAM: 4096},
+			want: []string{
+				"quota for RAM (MB) exceeded: 4096 required, 0 of 8192 available",
+				"quota for floating IPs exceeded: 1 required, 0 of 2 available",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkQuotas(tt.quotas, tt.required); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkQuotas() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClusterUsage(t *testing.T) {
+	flavor := &flavors.Flavor{VCPUs: 2, RAM: 4096}
+
+	tests := []struct {
+		name   string
+		args   k3s.ClusterArgs
+		flavor *flavors.Flavor
+		want   resourceUsage
+	}{
+		{
+			name:   "private",
+			args:   k3s.ClusterArgs{NodeCount: 3},
+			flavor: flavor,
+			want: resourceUsage{
+				quotaInstances:          3,
+				quotaPorts:              3,
+				quotaCores:              6,
+				quotaRAM:                12288,
+				quotaSecurityGroups:     1,
+				quotaSecurityGroupRules: 6,
+			},
+		},
+		{
+			name: "unknown flavor",
+			args: k3s.ClusterArgs{NodeCount: 1},
+			want: resourceUsage{
+				quotaInstances:          1,
+				quotaPorts:              1,
+				quotaSecurityGroups:     1,
+				quotaSecurityGroupRules: 6,
+			},
+		},
+		{
+			name:   "volumes",
+			args:   k3s.ClusterArgs{NodeCount: 2, VolumeSize: 50},
+			flavor: flavor,
+			want: resourceUsage{
+				quotaInstances:          2,
+				quotaPorts:              2,
+				quotaCores:              4,
+				quotaRAM:                8192,
+				quotaVolumes:            2,
+				quotaGigabytes:          100,
+				quotaSecurityGroups:     1,
+				quotaSecurityGroupRules: 6,
+			},
+		},
+		{
+			name:   "public with router port",
+			args:   k3s.ClusterArgs{NodeCount: 2, Public: true},
+			flavor: flavor,
+			want: resourceUsage{
+				quotaInstances:          2,
+				quotaPorts:              3,
+				quotaCores:              4,
+				quotaRAM:                8192,
+				quotaFloatingIPs:        2,
+				quotaNetworks:           1,
+				quotaSubnets:            1,
+				quotaRouters:            1,
+				quotaSecurityGroups:     1,
+				quotaSecurityGroupRules: 6,
+			},
+		},
+		{
+			name: "additional ports",
+			args: k3s.ClusterArgs{NodeCount: 1, AdditionalPorts: []int{8080, 9090}},
+			want: resourceUsage{
+				quotaInstances:          1,
+				quotaPorts:              1,
+				quotaSecurityGroups:     1,
+				quotaSecurityGroupRules: 8,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clusterUsage(&tt.args, tt.flavor); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("clusterUsage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStateUsage(t *testing.T) {
+	flavor := func(name string) (*flavors.Flavor, error) {
+		if name == "m4.large" {
+			return &flavors.Flavor{VCPUs: 2, RAM: 4096}, nil
+		}
+
+		return nil, nil
+	}
+
+	tests := []struct {
+		name       string
+		deployment string
+		want       resourceUsage
+	}{
+		{name: "empty state", want: resourceUsage{}},
+		{
+			name: "partially created public cluster",
+			deployment: `{"resources": [
+				{"type": "pulumi:pulumi:Stack"},
+				{"type": "pulumi:providers:openstack"},
+				{"type": "openstack:compute/keypair:Keypair"},
+				{"type": "openstack:networking/secGroup:SecGroup"},
+				{"type": "openstack:networking/secGroupRule:SecGroupRule"},
+				{"type": "openstack:networking/secGroupRule:SecGroupRule"},
+				{"type": "openstack:networking/network:Network"},
+				{"type": "openstack:networking/subnet:Subnet"},
+				{"type": "openstack:networking/router:Router"},
+				{"type": "openstack:networking/routerInterface:RouterInterface"},
+				{"type": "openstack:networking/floatingIp:FloatingIp"},
+				{"type": "openstack:compute/instance:Instance", "outputs": {"flavorName": "m4.large"}}
+			]}`,
+			want: resourceUsage{
+				quotaInstances:          1,
+				quotaPorts:              2,
+				quotaCores:              2,
+				quotaRAM:                4096,
+				quotaFloatingIPs:        1,
+				quotaNetworks:           1,
+				quotaSubnets:            1,
+				quotaRouters:            1,
+				quotaSecurityGroups:     1,
+				quotaSecurityGroupRules: 4,
+			},
+		},
+		{
+			name: "instances with volumes and unknown flavor",
+			deployment: `{"resources": [
+				{"type": "openstack:compute/instance:Instance", "outputs": {
+					"flavorName": "m4.large", "blockDevices": [{"destinationType": "volume", "volumeSize": 50}]
+				}},
+				{"type": "openstack:compute/instance:Instance", "outputs": {
+					"flavorName": "deleted", "blockDevices": [{"destinationType": "local", "volumeSize": 0}]
+				}}
+			]}`,
+			want: resourceUsage{
+				quotaInstances: 2,
+				quotaPorts:     2,
+				quotaCores:     2,
+				quotaRAM:       4096,
+				quotaVolumes:   1,
+				quotaGigabytes: 50,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := apitype.UntypedDeployment{Version: 3}
+			if tt.deployment != "" {
+				state.Deployment = []byte(tt.deployment)
+			}
+
+			got, err := stateUsage(state, flavor)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stateUsage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStateUsageFlavorError(t *testing.T) {
+	errLookup := errors.New("lookup failed")
+	state := apitype.UntypedDeployment{
+		Deployment: []byte(`{"resources": [{"type": "openstack:compute/instance:Instance", "outputs": {"flavorName": "m4.large"}}]}`),
+	}
+
+	_, err := stateUsage(state, func(string) (*flavors.Flavor, error) { return nil, errLookup })
+	if !errors.Is(err, errLookup) {
+		t.Errorf("stateUsage() error = %v, want %v", err, errLookup)
+	}
+}
+
+func TestCheckNetworks(t *testing.T) {
+	networks := []Network{
+		{ID: "private-id", Name: "internal"},
+		{ID: "public-id", Name: "public", External: true},
+		{ID: "pool-id", Name: "pool", External: true},
+	}
+
+	publicArgs := func(modify func(args *k3s.ClusterArgs)) *k3s.ClusterArgs {
+		args := &k3s.ClusterArgs{
+			Public:            true,
+			PublicNetworkName: "public",
+			PublicNetworkID:   "public-id",
+			PublicIPPool:      "pool",
+		}
+		if modify != nil {
+			modify(args)
+		}
+
+		return args
+	}
+
+	tests := []struct {
+		name string
+		args *k3s.ClusterArgs
+		want []string
+	}{
+		{name: "private", args: &k3s.ClusterArgs{PrivateNetworkName: "internal"}},
+		{
+			name: "private network missing",
+			args: &k3s.ClusterArgs{PrivateNetworkName: "missing"},
+			want: []string{`private network "missing" not found`},
+		},
+		{
+			name: "private network is ignored for public clusters",
+			args: publicArgs(func(args *k3s.ClusterArgs) { args.PrivateNetworkName = "missing" }),
+		},
+		{name: "public", args: publicArgs(nil)},
+		{
+			name: "public network missing",
+			args: publicArgs(func(args *k3s.ClusterArgs) { args.PublicNetworkName = "missing" }),
+			want: []string{`public network "missing" not found`},
+		},
+		{
+			name: "public network not external",
+			args: publicArgs(func(args *k3s.ClusterArgs) {
+				args.PublicNetworkName = "internal"
+				args.PublicNetworkID = "private-id"
+			}),
+			want: []string{`public network "internal" is not an external network`},
+		},
+		{
+			name: "public network ID mismatch",
+			args: publicArgs(func(args *k3s.ClusterArgs) { args.PublicNetworkID = "other-id" }),
+			want: []string{`public network "public" has the ID "public-id", not "other-id"`},
+		},
+		{
+			name: "IP pool not external",
+			args: publicArgs(func(args *k3s.ClusterArgs) { args.PublicIPPool = "internal" }),
+			want: []string{`public IP pool "internal" not found`},
+		},
+		{
+			name: "everything missing",
+			args: publicArgs(func(args *k3s.ClusterArgs) {
+				args.PublicNetworkName = "missing"
+				args.PublicIPPool = "missing"
+			}),
+			want: []string{`public network "missing" not found`, `public IP pool "missing" not found`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkNetworks(networks, tt.args); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkNetworks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
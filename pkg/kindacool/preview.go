@@ -0,0 +1,193 @@
+package kindacool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/brumhard/kindacool/pkg/k3s"
+
+	"github.com/pulumi/pulumi-openstack/sdk/v3/go/openstack"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optdestroy"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+var ErrChangesPending = errors.New("changes are pending")
+
+// PlannedChange is a single resource operation that would be executed.
+type PlannedChange struct {
+	Op   apitype.OpType `json:"op"`
+	Type string         `json:"type"`
+	Name string         `json:"name"`
+	// ReplaceReasons contains the properties that cause a replacement.
+	ReplaceReasons []string `json:"replaceReasons,omitempty"`
+}
+
+// Plan contains all the changes that would be executed when running the actual operation.
+type Plan struct {
+	Changes []PlannedChange `json:"changes"`
+}
+
+// HasChanges reports whether applying the plan would modify any resources.
+func (p *Plan) HasChanges() bool {
+	return len(p.Changes) > 0
+}
+
+// Count returns the amount of planned changes with the given operation.
+func (p *Plan) Count(op apitype.OpType) int {
+	count := 0
+	for _, change := range p.Changes {
+		if change.Op == op {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Preview shows which resources would be changed by Manager.Run with the given args.
+// If the stack doesn't exist yet, it is only created temporarily.
+// The config of existing stacks is not modified, the program gets the cloud's credentials directly.
+func (m *Manager) Preview(ctx context.Context, args *k3s.ClusterArgs) (*Plan, error) {
+	var (
+		cloud          *Cloud
+		legacyProvider string
+	)
+	// the program only runs during the preview, after the cloud and the legacy provider are known
+	program, err := m.program(args, func(ctx *pulumi.Context, name, version string) (*openstack.Provider, error) {
+		return newProvider(ctx, name, version, cloud.credentials, legacyProvider)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if cloud, err = m.clusterCloud(ctx); err != nil {
+		return nil, err
+	}
+
+	stackName := m.Options.Name
+
+	s, err := auto.SelectStackInlineSource(ctx, stackName, defaultProjectName, program, m.workspaceOptions()...)
+	if err != nil {
+		if !auto.IsSelectStack404Error(err) {
+			return nil, fmt.Errorf("failed to get stack: %w", err)
+		}
+
+		s, err = auto.NewStackInlineSource(ctx, stackName, defaultProjectName, program, m.workspaceOptions()...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stack: %w", err)
+		}
+
+		defer func() {
+			if err := s.Workspace().RemoveStack(context.WithoutCancel(ctx), stackName); err != nil {
+				m.Logger.Printf("Failed to remove temporary stack %q: %v\n", stackName, err)
+			}
+		}()
+	}
+
+	if legacyProvider, err = defaultProviderURN(ctx, s); err != nil {
+		return nil, err
+	}
+
+	if err := clearOpenStackEnv(s.Workspace()); err != nil {
+		return nil, err
+	}
+
+	m.LogStage(StagePlugins, "Installing required pulumi plugins")
+	if err := EnsurePlugins(ctx, s.Workspace(), m.Options.Plugins); err != nil {
+		return nil, err
+	}
+
+	m.LogStage(StagePreview, "Previewing changes")
+	eventStream, plan := collectPlan()
+	if _, err := s.Preview(ctx, optpreview.Refresh(), optpreview.EventStreams(eventStream)); err != nil {
+		return nil, fmt.Errorf("failed to preview stack: %w", err)
+	}
+
+	return plan(), nil
+}
+
+// PreviewDestroy shows which resources would be deleted by Manager.Destroy.
+// The resources are read with the providers in the stack's state, so the stack config is not modified.
+func (m *Manager) PreviewDestroy(ctx context.Context) (*Plan, error) {
+	w, err := m.newWorkspace(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.LogStage(StageStack, "Looking for cluster")
+	stack, err := auto.SelectStack(ctx, m.Options.Name, w)
+	if err != nil {
+		if auto.IsSelectStack404Error(err) {
+			m.Logger.Printf("No cluster with name %q could be found\n", m.Options.Name)
+			return &Plan{}, nil
+		}
+
+		return nil, err
+	}
+
+	// fails if the selected credentials belong to another cloud than the cluster's
+	if _, err := m.clusterCloud(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := clearOpenStackEnv(w); err != nil {
+		return nil, err
+	}
+
+	m.LogStage(StagePlugins, "Installing required pulumi plugins")
+	if err := EnsurePlugins(ctx, w, m.Options.Plugins); err != nil {
+		return nil, err
+	}
+
+	m.LogStage(StagePreview, "Previewing changes")
+	eventStream, plan := collectPlan()
+	if _, err := stack.PreviewDestroy(ctx, optdestroy.EventStreams(eventStream)); err != nil {
+		return nil, fmt.Errorf("failed to preview destroy: %w", err)
+	}
+
+	return plan(), nil
+}
+
+// collectPlan returns a channel to pass to the pulumi operation and a function
+// that returns the collected plan once the operation has finished.
+func collectPlan() (chan<- events.EngineEvent, func() *Plan) {
+	eventStream := make(chan events.EngineEvent)
+	done := make(chan struct{})
+	plan := &Plan{}
+
+	go func() {
+		defer close(done)
+		for event := range eventStream {
+			if event.ResourcePreEvent == nil {
+				continue
+			}
+
+			metadata := event.ResourcePreEvent.Metadata
+			switch metadata.Op {
+			case apitype.OpCreate, apitype.OpUpdate, apitype.OpReplace, apitype.OpDelete:
+			default:
+				// replacements also emit create-replacement and delete-replaced steps,
+				// everything else is not a change
+				continue
+			}
+
+			plan.Changes = append(plan.Changes, PlannedChange{
+				Op:             metadata.Op,
+				Type:           metadata.Type,
+				Name:           resource.URN(metadata.URN).Name(),
+				ReplaceReasons: metadata.Keys,
+			})
+		}
+	}()
+
+	return eventStream, func() *Plan {
+		<-done
+		return plan
+	}
+}
@@ -0,0 +1,311 @@
+package kindacool
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+// progressFlushTimeout limits how long to wait for the remaining events after an operation failed.
+// The automation API only closes the event stream if it started to tail the operation's event log,
+// which it doesn't if e.g. the language runtime server of the inline program can't be started.
+const progressFlushTimeout = 2 * time.Second
+
+// progress renders the engine events of a pulumi operation as one line per resource step.
+// If the manager logs in JSON format, the steps are emitted as resource events instead.
+// The failed resources are collected in any case to report them in the operation's error.
+type progress struct {
+	manager *Manager
+	// render is false if pulumi's own output is shown instead.
+	render   bool
+	started  map[string]time.Time
+	messages map[string][]string
+	failed   []apitype.StepEventMetadata
+	mu       sync.Mutex
+}
+
+// trackProgress returns a channel to pass to the pulumi operation and a function
+// that waits until all events are handled and returns the resources that failed.
+// The function gets the operation's error, the events of failed operations are only waited for
+// up to progressFlushTimeout and handled in the background afterwards.
+func (m *Manager) trackProgress(render bool) (chan<- events.EngineEvent, func(opErr error) []ResourceFailure) {
+	eventStream := make(chan events.EngineEvent)
+	done := make(chan struct{})
+	p := &progress{
+		manager:  m,
+		render:   render,
+		started:  map[string]time.Time{},
+		messages: map[string][]string{},
+	}
+
+	go func() {
+		defer close(done)
+		for event := range eventStream {
+			p.handle(event)
+		}
+	}()
+
+	return eventStream, func(opErr error) []ResourceFailure {
+		if opErr == nil {
+			// the event stream is closed before a successful operation returns
+			<-done
+			return p.failures()
+		}
+
+		select {
+		case <-done:
+		case <-time.After(progressFlushTimeout):
+		}
+
+		return p.failures()
+	}
+}
+
+// progressStreams returns the event stream for the operation and whether pulumi's own output should be shown.
+// Pulumi's output is only shown in verbose mode with text logs.
+func (m *Manager) progressStreams() (chan<- events.EngineEvent, func(opErr error) []ResourceFailure, bool) {
+	_, jsonOutput := m.eventWriter()
+	showPulumiOutput := m.Options.Verbose && !jsonOutput
+	eventStream, wait := m.trackProgress(!showPulumiOutput)
+
+	return eventStream, wait, showPulumiOutput
+}
+
+// failures returns the resources that failed in the order of their failure.
+// Errors of resources without a failed step, e.g. providers, are added at the end.
+func (p *progress) failures() []ResourceFailure {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var failures []ResourceFailure
+	reported := map[string]bool{}
+	for _, metadata := range p.failed {
+		reported[metadata.URN] = true
+		failures = append(failures, ResourceFailure{
+			Op:    metadata.Op,
+			Type:  metadata.Type,
+			Name:  resource.URN(metadata.URN).Name(),
+			Error: strings.Join(p.messages[metadata.URN], "\n"),
+		})
+	}
+
+	urns := make([]string, 0, len(p.messages))
+	for urn := range p.messages {
+		if !reported[urn] {
+			urns = append(urns, urn)
+		}
+	}
+	sort.Strings(urns)
+
+	for _, urn := range urns {
+		failures = append(failures, ResourceFailure{
+			Type:  string(resource.URN(urn).Type()),
+			Name:  resource.URN(urn).Name(),
+			Error: strings.Join(p.messages[urn], "\n"),
+		})
+	}
+
+	return failures
+}
+
+func (p *progress) handle(event events.EngineEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case event.ResourcePreEvent != nil:
+		metadata := event.ResourcePreEvent.Metadata
+		if !isTracked(metadata) {
+			return
+		}
+
+		p.started[stepKey(metadata)] = time.Now()
+		p.report(metadata, ResourceStarted)
+	case event.ResOutputsEvent != nil:
+		metadata := event.ResOutputsEvent.Metadata
+		if !isTracked(metadata) {
+			return
+		}
+
+		p.report(metadata, ResourceDone)
+	case event.ResOpFailedEvent != nil:
+		metadata := event.ResOpFailedEvent.Metadata
+		if !isTracked(metadata) {
+			return
+		}
+
+		p.failed = append(p.failed, metadata)
+		p.report(metadata, ResourceFailed)
+	case event.DiagnosticEvent != nil:
+		diagnostic := event.DiagnosticEvent
+		if diagnostic.Severity == "error" && diagnostic.URN != "" {
+			message := strings.TrimSpace(diagnostic.Message)
+			p.messages[diagnostic.URN] = append(p.messages[diagnostic.URN], message)
+		}
+	case event.SummaryEvent != nil:
+		p.summarize(event.SummaryEvent)
+	}
+}
+
+func (p *progress) report(metadata apitype.StepEventMetadata, state string) {
+	if !p.render {
+		return
+	}
+
+	var elapsed time.Duration
+	if state != ResourceStarted {
+		elapsed = p.elapsed(metadata)
+	}
+
+	var messages []string
+	if state == ResourceFailed {
+		messages = p.messages[metadata.URN]
+	}
+
+	if p.manager.emit(Event{
+		Type: EventResource,
+		Resource: &ResourceEvent{
+			Op:             metadata.Op,
+			Type:           metadata.Type,
+			Name:           resource.URN(metadata.URN).Name(),
+			State:          state,
+			ElapsedSeconds: elapsed.Seconds(),
+			Error:          strings.Join(messages, "\n"),
+		},
+	}) {
+		return
+	}
+
+	logger := p.manager.Logger
+	switch state {
+	case ResourceStarted:
+		logger.Printf("  %-10s %s\n", stepVerb(metadata.Op, false), resourceLabel(metadata.Type, resource.URN(metadata.URN).Name()))
+	case ResourceDone:
+		logger.Printf("✓ %-10s %s (%s)\n", stepVerb(metadata.Op, true), resourceLabel(metadata.Type, resource.URN(metadata.URN).Name()), elapsed)
+	case ResourceFailed:
+		logger.Printf("✗ %-10s %s (%s)\n", "failed", resourceLabel(metadata.Type, resource.URN(metadata.URN).Name()), elapsed)
+		for _, message := range messages {
+			logger.Printf("  %s\n", message)
+		}
+	}
+}
+
+func (p *progress) elapsed(metadata apitype.StepEventMetadata) time.Duration {
+	start, ok := p.started[stepKey(metadata)]
+	if !ok {
+		return 0
+	}
+
+	return time.Since(start).Round(time.Second)
+}
+
+func (p *progress) summarize(summary *apitype.SummaryEvent) {
+	if !p.render {
+		return
+	}
+
+	if p.manager.emit(Event{
+		Type: EventSummary,
+		Summary: &OperationSummary{
+			Changes:         summary.ResourceChanges,
+			DurationSeconds: summary.DurationSeconds,
+			Failed:          len(p.failed),
+		},
+	}) {
+		return
+	}
+
+	var changes []string
+	for _, op := range []apitype.OpType{apitype.OpCreate, apitype.OpUpdate, apitype.OpReplace, apitype.OpDelete} {
+		if count := summary.ResourceChanges[op]; count > 0 {
+			changes = append(changes, fmt.Sprintf("%d %s", count, stepVerb(op, true)))
+		}
+	}
+
+	if len(changes) == 0 {
+		changes = append(changes, "no changes")
+	}
+
+	duration := time.Duration(summary.DurationSeconds) * time.Second
+	p.manager.Logger.Printf("Summary: %s in %s\n", strings.Join(changes, ", "), duration)
+
+	if len(p.failed) > 0 {
+		p.manager.Logger.Printf("%d resource operation(s) failed\n", len(p.failed))
+	}
+}
+
+// isTracked reports whether the step changes an actual cloud resource.
+// Steps for the stack, components and providers are skipped.
+func isTracked(metadata apitype.StepEventMetadata) bool {
+	switch metadata.Op {
+	case apitype.OpCreate, apitype.OpUpdate, apitype.OpDelete, apitype.OpCreateReplacement, apitype.OpDeleteReplaced:
+	default:
+		return false
+	}
+
+	if strings.HasPrefix(metadata.Type, "pulumi:providers:") {
+		return false
+	}
+
+	state := metadata.New
+	if state == nil {
+		state = metadata.Old
+	}
+
+	return state != nil && state.Custom
+}
+
+func stepKey(metadata apitype.StepEventMetadata) string {
+	return string(metadata.Op) + "/" + metadata.URN
+}
+
+// stepVerb returns the verb of an operation in progressive or past tense.
+func stepVerb(op apitype.OpType, done bool) string {
+	verbs := map[apitype.OpType][2]string{
+		apitype.OpCreate:            {"creating", "created"},
+		apitype.OpUpdate:            {"updating", "updated"},
+		apitype.OpDelete:            {"deleting", "deleted"},
+		apitype.OpReplace:           {"replacing", "replaced"},
+		apitype.OpCreateReplacement: {"replacing", "replaced"},
+		apitype.OpDeleteReplaced:    {"cleaning", "cleaned"},
+	}
+
+	verb, ok := verbs[op]
+	if !ok {
+		return string(op)
+	}
+
+	if done {
+		return verb[1]
+	}
+
+	return verb[0]
+}
+
+// resourceLabel returns a short human readable description of the resource, e.g. "vm kindacool-0".
+func resourceLabel(resourceType, name string) string {
+	var kind string
+	switch resourceType {
+	case "openstack:compute/instance:Instance":
+		kind = "vm"
+	case "openstack:networking/floatingIp:FloatingIp":
+		kind = "floating ip"
+	case "openstack:compute/floatingIpAssociate:FloatingIpAssociate":
+		kind = "floating ip association"
+	case "command:remote:Command":
+		kind = "install step"
+	default:
+		kind = resourceType
+		if i := strings.LastIndex(kind, ":"); i >= 0 {
+			kind = kind[i+1:]
+		}
+	}
+
+	return fmt.Sprintf("%s %s", kind, name)
+}
@@ -0,0 +1,38 @@
+package kindacool
+
+import (
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestTrackProgressWithoutEvents(t *testing.T) {
+	m := &Manager{Logger: log.New(io.Discard, "", 0)}
+
+	t.Run("closed stream", func(t *testing.T) {
+		eventStream, wait := m.trackProgress(false)
+		close(eventStream)
+
+		if failures := wait(nil); len(failures) > 0 {
+			t.Errorf("wait() = %v, want no failures", failures)
+		}
+	})
+
+	t.Run("stream never closed by a failed operation", func(t *testing.T) {
+		_, wait := m.trackProgress(false)
+
+		returned := make(chan struct{})
+		go func() {
+			defer close(returned)
+			wait(errors.New("failed to start the language runtime server"))
+		}()
+
+		select {
+		case <-returned:
+		case <-time.After(progressFlushTimeout + 5*time.Second):
+			t.Fatal("wait() didn't return for a failed operation")
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package kindacool
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+
+	"github.com/blang/semver"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// the range of pulumi CLI versions the automation API calls are known to work with.
+const (
+	minPulumiVersion = "3.100.0"
+	maxPulumiMajor   = 3
+)
+
+var ErrUnsupportedPulumi = errors.New("unsupported pulumi CLI version")
+
+// PulumiVersion returns the version of the pulumi CLI in $PATH.
+// If there's none, the version of the managed pulumi CLI is returned if it's installed.
+func PulumiVersion() (semver.Version, error) {
+	opts := &auto.PulumiCommandOptions{SkipVersionCheck: true}
+	if _, err := exec.LookPath("pulumi"); err != nil {
+		if root, err := managedPulumiRoot(); err == nil {
+			opts.Root = root
+		}
+	}
+
+	command, err := auto.NewPulumiCommand(opts)
+	if err != nil {
+		return semver.Version{}, fmt.Errorf("%w: %v", ErrPulumiNotInPath, err)
+	}
+
+	return command.Version(), nil
+}
+
+// CheckPulumiVersion returns an error if the version is outside of the supported range.
+func CheckPulumiVersion(version semver.Version) error {
+	if version.LT(semver.MustParse(minPulumiVersion)) || version.Major > maxPulumiMajor {
+		return fmt.Errorf(
+			"%w: %s, supported are versions from %s up to the next major version",
+			ErrUnsupportedPulumi, version, minPulumiVersion,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,348 @@
+package kindacool
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+const (
+	// ManagedPulumiVersion is the version of the pulumi CLI that is installed if there's none in $PATH.
+	// Keep it in sync with the version of the pulumi SDK in go.mod.
+	ManagedPulumiVersion = "3.131.0"
+	// DefaultPulumiMirror is the base URL of pulumi's official release archives.
+	DefaultPulumiMirror = "https://get.pulumi.com/releases/sdk"
+)
+
+var (
+	ErrPulumiInstall   = errors.New("failed to install the pulumi CLI")
+	ErrChecksumMissing = errors.New("no checksum found for the pulumi archive")
+	ErrChecksumInvalid = errors.New("checksum of the pulumi archive doesn't match")
+)
+
+// PulumiOptions configures how the managed pulumi CLI is installed.
+// It's only used if there's no pulumi CLI in $PATH.
+type PulumiOptions struct {
+	// Mirror is the base URL the release archive and its checksums file are downloaded from.
+	// It defaults to DefaultPulumiMirror.
+	Mirror string `json:"mirror,omitempty"`
+	// Archive is a local release archive that is installed instead of downloading it.
+	Archive string `json:"archive,omitempty"`
+	// Checksum is the SHA-256 checksum of the archive, it's required for other mirrors than DefaultPulumiMirror.
+	// Without it the checksum is looked up in the release's checksums file from the mirror or next to the archive.
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// ensurePulumi returns the pulumi CLI in $PATH or the one managed by kindacool.
+// The managed CLI is installed into the user's cache directory if it's not installed yet.
+func ensurePulumi(ctx context.Context, opts PulumiOptions, logger *log.Logger) (auto.PulumiCommand, error) {
+	if _, err := exec.LookPath("pulumi"); err == nil {
+		return auto.NewPulumiCommand(&auto.PulumiCommandOptions{})
+	}
+
+	root, err := managedPulumiRoot()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPulumiNotInPath, err)
+	}
+
+	managedOpts := &auto.PulumiCommandOptions{Root: root, Version: semver.MustParse(ManagedPulumiVersion)}
+	if command, err := auto.NewPulumiCommand(managedOpts); err == nil {
+		return command, nil
+	}
+
+	logger.Printf("No pulumi CLI found in $PATH, installing pulumi v%s to %s\n", ManagedPulumiVersion, root)
+	if err := installPulumi(ctx, opts, root); err != nil {
+		return nil, err
+	}
+
+	return auto.NewPulumiCommand(managedOpts)
+}
+
+// managedPulumiRoot returns the directory of the managed pulumi CLI.
+// The binaries are located in its bin directory as expected by auto.NewPulumiCommand.
+func managedPulumiRoot() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(cacheDir, defaultProjectName, "pulumi", ManagedPulumiVersion), nil
+}
+
+// installPulumi verifies and extracts the release archive into root.
+// The archive is extracted into a temporary directory first, so that an interrupted install is never used.
+func installPulumi(ctx context.Context, opts PulumiOptions, root string) error {
+	// another mirror could serve a manipulated checksums file along with the archive
+	if opts.Checksum == "" && opts.Archive == "" && opts.mirror() != DefaultPulumiMirror {
+		return fmt.Errorf("%w: a checksum is required for archives from %s", ErrChecksumMissing, opts.mirror())
+	}
+
+	archive, cleanup, err := opts.fetchArchive(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPulumiInstall, err)
+	}
+	defer cleanup()
+
+	if err := opts.verifyChecksum(ctx, archive); err != nil {
+		return err
+	}
+
+	//nolint:gomnd // well-known directory permissions
+	if err := os.MkdirAll(filepath.Dir(root), 0755); err != nil {
+		return fmt.Errorf("%w: %w", ErrPulumiInstall, err)
+	}
+
+	tmpRoot, err := os.MkdirTemp(filepath.Dir(root), ".install-")
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPulumiInstall, err)
+	}
+	defer os.RemoveAll(tmpRoot)
+
+	if err := extractPulumi(archive, filepath.Join(tmpRoot, "bin")); err != nil {
+		return fmt.Errorf("%w: %w", ErrPulumiInstall, err)
+	}
+
+	// another process might have installed it in the meantime
+	_ = os.RemoveAll(root)
+	if err := os.Rename(tmpRoot, root); err != nil {
+		return fmt.Errorf("%w: %w", ErrPulumiInstall, err)
+	}
+
+	return nil
+}
+
+// pulumiArchiveName returns the name of the release archive for the current platform.
+func pulumiArchiveName() string {
+	arch := runtime.GOARCH
+	if arch == "amd64" {
+		arch = "x64"
+	}
+
+	extension := "tar.gz"
+	if runtime.GOOS == "windows" {
+		extension = "zip"
+	}
+
+	return fmt.Sprintf("pulumi-v%s-%s-%s.%s", ManagedPulumiVersion, runtime.GOOS, arch, extension)
+}
+
+func (o PulumiOptions) mirror() string {
+	if o.Mirror == "" {
+		return DefaultPulumiMirror
+	}
+
+	return strings.TrimSuffix(o.Mirror, "/")
+}
+
+// fetchArchive returns the path to the configured archive or downloads it from the mirror.
+// The returned function removes the downloaded file.
+func (o PulumiOptions) fetchArchive(ctx context.Context) (string, func(), error) {
+	if o.Archive != "" {
+		return o.Archive, func() {}, nil
+	}
+
+	file, err := os.CreateTemp("", "*-"+pulumiArchiveName())
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.Remove(file.Name()) }
+	defer file.Close()
+
+	if err := fetchURL(ctx, fmt.Sprintf("%s/%s", o.mirror(), pulumiArchiveName()), file); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return file.Name(), cleanup, nil
+}
+
+// verifyChecksum compares the archive's checksum with the configured one or the one from the release's checksums file.
+func (o PulumiOptions) verifyChecksum(ctx context.Context, archive string) error {
+	expected := strings.ToLower(o.Checksum)
+	if expected == "" {
+		var err error
+		if expected, err = o.lookupChecksum(ctx, archive); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Open(archive)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPulumiInstall, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return fmt.Errorf("%w: %w", ErrPulumiInstall, err)
+	}
+
+	if actual := hex.EncodeToString(hash.Sum(nil)); actual != expected {
+		return fmt.Errorf("%w: expected %s, got %s", ErrChecksumInvalid, expected, actual)
+	}
+
+	return nil
+}
+
+// lookupChecksum finds the archive's checksum in the release's checksums file.
+// For a local archive the file is expected next to it, otherwise it's downloaded from the mirror.
+func (o PulumiOptions) lookupChecksum(ctx context.Context, archive string) (string, error) {
+	checksumsName := fmt.Sprintf("pulumi-%s-checksums.txt", ManagedPulumiVersion)
+	archiveName := pulumiArchiveName()
+
+	var checksums io.Reader
+	if o.Archive != "" {
+		archiveName = filepath.Base(archive)
+		file, err := os.Open(filepath.Join(filepath.Dir(archive), checksumsName))
+		if err != nil {
+			return "", fmt.Errorf("%w: set a checksum or put %s next to the archive: %v", ErrChecksumMissing, checksumsName, err)
+		}
+		defer file.Close()
+		checksums = file
+	} else {
+		var content strings.Builder
+		if err := fetchURL(ctx, fmt.Sprintf("%s/%s", o.mirror(), checksumsName), &content); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrChecksumMissing, err)
+		}
+		checksums = strings.NewReader(content.String())
+	}
+
+	scanner := bufio.NewScanner(checksums)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		//nolint:gomnd // lines consist of the checksum and the file name
+		if len(fields) == 2 && fields[1] == archiveName {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s is not listed in %s", ErrChecksumMissing, archiveName, checksumsName)
+}
+
+// fetchURL writes the content of the URL to w.
+func fetchURL(ctx context.Context, url string, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s returned %s", ErrPulumiInstall, url, resp.Status)
+	}
+
+	_, err = io.Copy(w, resp.Body)
+
+	return err
+}
+
+// extractPulumi writes the binaries of the release archive into binDir.
+// The archives contain the binaries in a pulumi or pulumi/bin directory, so only the file names are kept.
+func extractPulumi(archive, binDir string) error {
+	//nolint:gomnd // well-known directory permissions
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(archive, ".zip") {
+		return extractZip(archive, binDir)
+	}
+
+	return extractTarGz(archive, binDir)
+}
+
+func extractTarGz(archive, binDir string) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if err := writeBinary(filepath.Join(binDir, filepath.Base(header.Name)), reader); err != nil {
+			return err
+		}
+	}
+}
+
+func extractZip(archive, binDir string) error {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		content, err := file.Open()
+		if err != nil {
+			return err
+		}
+
+		err = writeBinary(filepath.Join(binDir, filepath.Base(file.Name)), content)
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeBinary(path string, content io.Reader) error {
+	//nolint:gomnd // executable file permissions
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	//nolint:gosec // the archive is verified by its checksum
+	_, err = io.Copy(file, content)
+
+	return err
+}
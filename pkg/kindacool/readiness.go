@@ -0,0 +1,190 @@
+package kindacool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// DefaultWaitTimeout is the time to wait for a new cluster to become ready.
+	DefaultWaitTimeout = 5 * time.Minute
+	readinessInterval  = 5 * time.Second
+	// readinessRequestTimeout keeps a single request from using up the whole timeout if the API server hangs.
+	readinessRequestTimeout = 10 * time.Second
+	kubeSystemNamespace     = "kube-system"
+)
+
+var ErrNotReady = errors.New("cluster did not become ready")
+
+// coreWorkloads are the deployments in kube-system that k3s installs by default.
+var coreWorkloads = []string{"coredns", "local-path-provisioner", "metrics-server"}
+
+var (
+	nodesResource       = schema.GroupVersionResource{Version: "v1", Resource: "nodes"}
+	deploymentsResource = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+)
+
+// Readiness is the state of the cluster's nodes and core workloads.
+type Readiness struct {
+	ExpectedNodes int `json:"expectedNodes"`
+	ReadyNodes    int `json:"readyNodes"`
+	// NotReadyNodes are the registered nodes that are not ready yet.
+	NotReadyNodes []string `json:"notReadyNodes,omitempty"`
+	// UnavailableWorkloads are the core workloads in kube-system that are missing or not available yet.
+	UnavailableWorkloads []string `json:"unavailableWorkloads,omitempty"`
+	// Error is the last error while talking to the API server, e.g. because it's still starting.
+	Error string `json:"error,omitempty"`
+}
+
+// Ready reports whether all expected nodes are ready and all core workloads are available.
+func (r Readiness) Ready() bool {
+	return r.Error == "" && r.ReadyNodes >= r.ExpectedNodes && len(r.UnavailableWorkloads) == 0
+}
+
+func (r Readiness) String() string {
+	if r.Error != "" {
+		return fmt.Sprintf("API server not reachable: %s", r.Error)
+	}
+
+	state := fmt.Sprintf("%d/%d nodes ready", r.ReadyNodes, r.ExpectedNodes)
+	if len(r.NotReadyNodes) > 0 {
+		state += fmt.Sprintf(" (not ready: %s)", strings.Join(r.NotReadyNodes, ", "))
+	}
+
+	if len(r.UnavailableWorkloads) > 0 {
+		state += fmt.Sprintf(", waiting for %s", strings.Join(r.UnavailableWorkloads, ", "))
+	}
+
+	return state
+}
+
+// WaitReady waits until all nodes of the cluster are ready and the core workloads in kube-system are available.
+// The cluster's kubeconfig is used if kubeconfig is empty,
+// another one is needed if the API server is only reachable through a tunnel.
+func (m *Manager) WaitReady(ctx context.Context, kubeconfig []byte, timeout time.Duration) (Readiness, error) {
+	description, err := m.Describe(ctx)
+	if err != nil {
+		return Readiness{}, err
+	}
+
+	// clusters created by older versions don't export their nodes
+	expectedNodes := len(description.Nodes)
+	if expectedNodes == 0 && description.Args != nil {
+		expectedNodes = description.Args.NodeCount
+	}
+
+	if len(kubeconfig) == 0 {
+		content, err := m.FetchOutput(ctx, OutputKubeconfig)
+		if err != nil {
+			return Readiness{}, err
+		}
+		kubeconfig = []byte(content)
+	}
+
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return Readiness{}, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+	config.Timeout = readinessRequestTimeout
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return Readiness{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var lastState string
+	for {
+		readiness := checkReadiness(ctx, client, expectedNodes)
+		if readiness.Ready() {
+			m.Logger.Printf("Cluster is ready: %s\n", readiness)
+			return readiness, nil
+		}
+
+		if state := readiness.String(); state != lastState {
+			m.Logger.Printf("Waiting for the cluster: %s\n", state)
+			lastState = state
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return readiness, fmt.Errorf("%w within %s: %s", ErrNotReady, timeout, readiness)
+			}
+
+			return readiness, ctx.Err()
+		case <-time.After(readinessInterval):
+		}
+	}
+}
+
+// checkReadiness returns the current state of the cluster's nodes and core workloads.
+func checkReadiness(ctx context.Context, client dynamic.Interface, expectedNodes int) Readiness {
+	readiness := Readiness{ExpectedNodes: expectedNodes}
+
+	nodes, err := client.Resource(nodesResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		readiness.Error = err.Error()
+		return readiness
+	}
+
+	for _, node := range nodes.Items {
+		if nodeReady(node) {
+			readiness.ReadyNodes++
+		} else {
+			readiness.NotReadyNodes = append(readiness.NotReadyNodes, node.GetName())
+		}
+	}
+
+	for _, name := range coreWorkloads {
+		deployment, err := client.Resource(deploymentsResource).Namespace(kubeSystemNamespace).Get(ctx, name, metav1.GetOptions{})
+		// k3s creates the deployments shortly after the API server is started, so missing ones are waited for as well
+		if err != nil || !deploymentAvailable(deployment) {
+			readiness.UnavailableWorkloads = append(readiness.UnavailableWorkloads, name)
+		}
+	}
+
+	return readiness
+}
+
+// nodeReady reports whether the node's Ready condition is true.
+func nodeReady(node unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(node.Object, "status", "conditions")
+	for _, condition := range conditions {
+		fields, ok := condition.(map[string]interface{})
+		if ok && fields["type"] == "Ready" {
+			return fields["status"] == "True"
+		}
+	}
+
+	return false
+}
+
+// deploymentAvailable reports whether the current generation of the deployment is rolled out
+// and all of its replicas are available.
+func deploymentAvailable(deployment *unstructured.Unstructured) bool {
+	observedGeneration, _, _ := unstructured.NestedInt64(deployment.Object, "status", "observedGeneration")
+	if observedGeneration < deployment.GetGeneration() {
+		return false
+	}
+
+	replicas, found, _ := unstructured.NestedInt64(deployment.Object, "spec", "replicas")
+	if !found {
+		replicas = 1
+	}
+
+	available, _, _ := unstructured.NestedInt64(deployment.Object, "status", "availableReplicas")
+
+	return available >= replicas
+}
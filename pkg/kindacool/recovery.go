@@ -0,0 +1,121 @@
+package kindacool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+const pendingOperationsKey = "pending_operations"
+
+// PendingOperation is a resource operation that was started but never finished,
+// e.g. because the update was canceled or the process was killed.
+type PendingOperation struct {
+	Op   apitype.OperationType `json:"op"`
+	Type string                `json:"type"`
+	Name string                `json:"name"`
+	// ID is the provider ID of the resource, if it is already known.
+	ID string `json:"id,omitempty"`
+}
+
+// HalfCreated reports whether the resource might exist in OpenStack
+// without being tracked in the stack's state.
+func (o PendingOperation) HalfCreated() bool {
+	return o.Op == apitype.OperationTypeCreating
+}
+
+// Cancel stops the update that is currently running for the cluster.
+// It returns the operations that were pending afterwards.
+func (m *Manager) Cancel(ctx context.Context) ([]PendingOperation, error) {
+	stack, err := m.selectStack(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Logger.Println("Canceling the running update")
+	if err := stack.Cancel(ctx); err != nil {
+		return nil, err
+	}
+
+	deployment, err := stack.Export(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export stack: %w", err)
+	}
+
+	operations, _, err := pendingOperations(deployment)
+	return operations, err
+}
+
+// Unlock removes all pending operations from the cluster's state
+// so that following updates don't fail because of them.
+// It returns the operations that were removed.
+func (m *Manager) Unlock(ctx context.Context) ([]PendingOperation, error) {
+	stack, err := m.selectStack(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Logger.Println("Exporting stack state")
+	deployment, err := stack.Export(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export stack: %w", err)
+	}
+
+	operations, rawDeployment, err := pendingOperations(deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(operations) == 0 {
+		m.Logger.Println("No pending operations found")
+		return nil, nil
+	}
+
+	// only the pending operations are removed to keep everything else in the state untouched
+	delete(rawDeployment, pendingOperationsKey)
+	deployment.Deployment, err = json.Marshal(rawDeployment)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Logger.Printf("Removing %d pending operations from the stack state\n", len(operations))
+	if err := stack.Import(ctx, deployment); err != nil {
+		return nil, fmt.Errorf("failed to import stack: %w", err)
+	}
+
+	return operations, nil
+}
+
+// pendingOperations extracts the pending operations from the deployment.
+// It also returns the raw deployment to allow modifications without losing unknown fields.
+func pendingOperations(deployment apitype.UntypedDeployment) ([]PendingOperation, map[string]json.RawMessage, error) {
+	rawDeployment := map[string]json.RawMessage{}
+	if err := json.Unmarshal(deployment.Deployment, &rawDeployment); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode deployment: %w", err)
+	}
+
+	rawOperations, ok := rawDeployment[pendingOperationsKey]
+	if !ok {
+		return nil, rawDeployment, nil
+	}
+
+	var operations []apitype.OperationV2
+	if err := json.Unmarshal(rawOperations, &operations); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode pending operations: %w", err)
+	}
+
+	pending := make([]PendingOperation, 0, len(operations))
+	for _, operation := range operations {
+		pending = append(pending, PendingOperation{
+			Op:   operation.Type,
+			Type: string(operation.Resource.Type),
+			Name: resource.URN(operation.Resource.URN).Name(),
+			ID:   string(operation.Resource.ID),
+		})
+	}
+
+	return pending, rawDeployment, nil
+}
@@ -0,0 +1,170 @@
+package kindacool
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+const (
+	// DefaultRetries is the number of times a pulumi operation is retried after a transient error.
+	DefaultRetries = 2
+	// initialRetryBackoff is the time to wait before the first retry, it's doubled for every following retry.
+	initialRetryBackoff = 15 * time.Second
+	maxRetryBackoff     = 2 * time.Minute
+	// stackType is the type of the root resource, its errors only repeat the errors of the program or the resources.
+	stackType = "pulumi:pulumi:Stack"
+)
+
+// transientErrorPattern matches the errors of OpenStack APIs and SSH connections that are worth a retry,
+// e.g. an overloaded API or a VM that is still booting.
+var transientErrorPattern = regexp.MustCompile(`(?i)` + strings.Join([]string{
+	`but got (429|500|502|503|504) instead`,
+	`service unavailable`,
+	`bad gateway`,
+	`gateway time-?out`,
+	`too many requests`,
+	`connection reset by peer`,
+	`connection refused`,
+	`no route to host`,
+	`i/o timeout`,
+	`tls handshake timeout`,
+	`unexpected eof`,
+	`ssh: handshake failed`,
+	`failed to dial`,
+}, "|"))
+
+// ResourceFailure describes a resource operation that failed during a pulumi operation.
+type ResourceFailure struct {
+	Op    apitype.OpType `json:"op,omitempty"`
+	Type  string         `json:"type"`
+	Name  string         `json:"name"`
+	Error string         `json:"error"`
+}
+
+func (f ResourceFailure) String() string {
+	return fmt.Sprintf("%s: %s", resourceLabel(f.Type, f.Name), f.Error)
+}
+
+// DeploymentError is returned if a pulumi operation failed.
+// It matches the sentinel of the operation, e.g. ErrUpdateFailed, with errors.Is.
+type DeploymentError struct {
+	sentinel error
+	err      error
+	// Failures are the resources that failed, it's empty if the operation failed before any resource was changed.
+	Failures []ResourceFailure
+	// Attempts is the number of times the operation was run.
+	Attempts int
+}
+
+func (e *DeploymentError) Error() string {
+	sentinel := e.sentinel.Error()
+	if e.Attempts > 1 {
+		sentinel = fmt.Sprintf("%s after %d attempts", sentinel, e.Attempts)
+	}
+
+	failures := e.resourceFailures()
+	if len(failures) == 0 {
+		return fmt.Sprintf("%s: %v", sentinel, e.err)
+	}
+
+	lines := make([]string, 0, len(failures))
+	for _, failure := range failures {
+		lines = append(lines, "  "+failure.String())
+	}
+
+	return fmt.Sprintf("%s: %d resource(s) failed:\n%s", sentinel, len(failures), strings.Join(lines, "\n"))
+}
+
+func (e *DeploymentError) Unwrap() []error {
+	return []error{e.sentinel, e.err}
+}
+
+// Transient reports whether the operation failed only because of errors that are likely gone on the next attempt.
+func (e *DeploymentError) Transient() bool {
+	failures := e.resourceFailures()
+	if len(failures) == 0 {
+		return transientErrorPattern.MatchString(e.err.Error())
+	}
+
+	for _, failure := range failures {
+		if !transientErrorPattern.MatchString(failure.Error) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// resourceFailures returns the failures of actual resources.
+// The failure of the stack is only returned if no resource failed, e.g. for errors of the program.
+func (e *DeploymentError) resourceFailures() []ResourceFailure {
+	var failures []ResourceFailure
+	for _, failure := range e.Failures {
+		if failure.Type != stackType {
+			failures = append(failures, failure)
+		}
+	}
+
+	if len(failures) == 0 {
+		return e.Failures
+	}
+
+	return failures
+}
+
+// operationError wraps the error of a pulumi operation to be distinguishable by its cause.
+func operationError(sentinel, err error, failures []ResourceFailure) error {
+	if auto.IsConcurrentUpdateError(err) {
+		return fmt.Errorf("%w: %w", ErrStackLocked, err)
+	}
+
+	return &DeploymentError{sentinel: sentinel, err: err, Failures: failures, Attempts: 1}
+}
+
+// retry runs the operation until it succeeds, fails with an error that is not transient or all retries are used.
+func (m *Manager) retry(ctx context.Context, retries int, operation func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := operation()
+
+		deploymentErr, ok := err.(*DeploymentError) //nolint:errorlint // operations return the error unwrapped
+		if !ok {
+			return err
+		}
+
+		deploymentErr.Attempts = attempt
+		if attempt > retries || !deploymentErr.Transient() || ctx.Err() != nil {
+			return deploymentErr
+		}
+
+		backoff := retryBackoff(attempt)
+		m.LogStage(StageRetry, fmt.Sprintf(
+			"Retrying in %s after a transient error (retry %d of %d): %v", backoff, attempt, retries, deploymentErr,
+		))
+
+		select {
+		case <-ctx.Done():
+			return deploymentErr
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// retryBackoff returns the time to wait before the given retry.
+func retryBackoff(retry int) time.Duration {
+	backoff := initialRetryBackoff
+	for i := 1; i < retry && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+
+	return backoff
+}
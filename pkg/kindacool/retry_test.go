@@ -0,0 +1,158 @@
+package kindacool
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTransientErrorPattern(t *testing.T) {
+	tests := []struct {
+		message string
+		want    bool
+	}{
+		{"Expected HTTP response code [200] when accessing [GET https://compute/servers], but got 503 instead", true},
+		{"but got 429 instead", true},
+		{"but got 404 instead", false},
+		{"Service Unavailable", true},
+		{"502 Bad Gateway", true},
+		{"504 Gateway Time-out", true},
+		{"gateway timeout", true},
+		{"Too Many Requests", true},
+		{"read tcp 10.0.0.1:22: connection reset by peer", true},
+		{"dial tcp 10.0.0.1:22: connect: connection refused", true},
+		{"dial tcp 10.0.0.1:22: connect: no route to host", true},
+		{"dial tcp 10.0.0.1:22: i/o timeout", true},
+		{"net/http: TLS handshake timeout", true},
+		{"unexpected EOF", true},
+		{"ssh: handshake failed: EOF", true},
+		{"Failed to dial: dial tcp 10.0.0.1:22", true},
+		{"Quota exceeded for instances: Requested 1, but already used 10 of 10 instances", false},
+		{"Flavor m4.huge could not be found", false},
+		{"ssh: unable to authenticate, attempted methods [none publickey]", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			if got := transientErrorPattern.MatchString(tt.message); got != tt.want {
+				t.Errorf("transientErrorPattern.MatchString(%q) = %v, want %v", tt.message, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeploymentErrorTransient(t *testing.T) {
+	stackFailure := ResourceFailure{Type: stackType, Name: "kindacool-kindacool", Error: "update failed"}
+	transientFailure := ResourceFailure{Type: "command:remote:Command", Name: "k3s", Error: "ssh: handshake failed: EOF"}
+	permanentFailure := ResourceFailure{
+		Type:  "openstack:compute/instance:Instance",
+		Name:  "kindacool-node-0",
+		Error: "Flavor m4.huge could not be found",
+	}
+
+	tests := []struct {
+		name         string
+		err          error
+		failures     []ResourceFailure
+		wantFailures []ResourceFailure
+		want         bool
+	}{
+		{
+			name: "transient error without failures",
+			err:  errors.New("dial tcp: i/o timeout"),
+			want: true,
+		},
+		{
+			name: "permanent error without failures",
+			err:  errors.New("program failed"),
+		},
+		{
+			name:         "stack only",
+			err:          errors.New("exit status 255"),
+			failures:     []ResourceFailure{{Type: stackType, Name: "kindacool-kindacool", Error: "Service Unavailable"}},
+			wantFailures: []ResourceFailure{{Type: stackType, Name: "kindacool-kindacool", Error: "Service Unavailable"}},
+			want:         true,
+		},
+		{
+			name:         "the stack's failure is ignored",
+			err:          errors.New("exit status 255"),
+			failures:     []ResourceFailure{stackFailure, transientFailure},
+			wantFailures: []ResourceFailure{transientFailure},
+			want:         true,
+		},
+		{
+			name:         "transient and permanent failures",
+			err:          errors.New("exit status 255 Service Unavailable"),
+			failures:     []ResourceFailure{transientFailure, permanentFailure, stackFailure},
+			wantFailures: []ResourceFailure{transientFailure, permanentFailure},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deploymentErr := &DeploymentError{sentinel: ErrUpdateFailed, err: tt.err, Failures: tt.failures, Attempts: 1}
+
+			if got := deploymentErr.resourceFailures(); !reflect.DeepEqual(got, tt.wantFailures) {
+				t.Errorf("resourceFailures() = %v, want %v", got, tt.wantFailures)
+			}
+
+			if got := deploymentErr.Transient(); got != tt.want {
+				t.Errorf("Transient() = %v, want %v", got, tt.want)
+			}
+
+			if !errors.Is(deploymentErr, ErrUpdateFailed) || !errors.Is(deploymentErr, tt.err) {
+				t.Errorf("%v doesn't match its sentinel and cause", deploymentErr)
+			}
+		})
+	}
+}
+
+func TestDeploymentErrorMessage(t *testing.T) {
+	failure := ResourceFailure{Type: "command:remote:Command", Name: "k3s", Error: "ssh: handshake failed: EOF"}
+
+	tests := []struct {
+		name string
+		err  *DeploymentError
+		want string
+	}{
+		{
+			name: "without failures",
+			err:  &DeploymentError{sentinel: ErrUpdateFailed, err: errors.New("program failed"), Attempts: 1},
+			want: "failed to update stack: program failed",
+		},
+		{
+			name: "with failures after retries",
+			err:  &DeploymentError{sentinel: ErrUpdateFailed, err: errors.New("exit status 255"), Failures: []ResourceFailure{failure}, Attempts: 3},
+			want: "failed to update stack after 3 attempts: 1 resource(s) failed:\n  " + failure.String(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		retry int
+		want  time.Duration
+	}{
+		{1, 15 * time.Second},
+		{2, 30 * time.Second},
+		{3, time.Minute},
+		{4, 2 * time.Minute},
+		{5, 2 * time.Minute},
+		{100, 2 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := retryBackoff(tt.retry); got != tt.want {
+			t.Errorf("retryBackoff(%d) = %s, want %s", tt.retry, got, tt.want)
+		}
+	}
+}
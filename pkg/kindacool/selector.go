@@ -0,0 +1,63 @@
+package kindacool
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Selector matches clusters based on their tags or nodes based on their attributes.
+// Every key has to be present in the tags, if a value is set it has to match as well.
+type Selector map[string]string
+
+// ParseSelector parses a comma separated list of requirements like "owner=alice,ttl-expired".
+// Requirements without a value only check for the existence of the tag.
+func ParseSelector(selector string) (Selector, error) {
+	parsed := Selector{}
+	if strings.TrimSpace(selector) == "" {
+		return parsed, nil
+	}
+
+	for _, requirement := range strings.Split(selector, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(requirement), "=")
+		if key == "" {
+			return nil, fmt.Errorf("%w: selector %q contains an empty key", ErrInvalidConfig, selector)
+		}
+
+		parsed[key] = value
+	}
+
+	return parsed, nil
+}
+
+// Matches checks whether all the requirements of the selector are fulfilled by the tags.
+func (s Selector) Matches(tags map[string]string) bool {
+	for key, value := range s {
+		tagValue, ok := tags[key]
+		if !ok {
+			return false
+		}
+
+		if value != "" && tagValue != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s Selector) String() string {
+	requirements := make([]string, 0, len(s))
+	for key, value := range s {
+		if value == "" {
+			requirements = append(requirements, key)
+			continue
+		}
+
+		requirements = append(requirements, key+"="+value)
+	}
+
+	sort.Strings(requirements)
+
+	return strings.Join(requirements, ",")
+}
@@ -0,0 +1,262 @@
+package kindacool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/brumhard/kindacool/pkg/k3s"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	SpecAPIVersion = "kindacool.brumhard.com/v1alpha1"
+	SpecKind       = "Cluster"
+)
+
+// ClusterSpec is the declarative description of a cluster as used in spec files.
+// All settings that are not set in the spec use the values from DefaultClusterArgs.
+type ClusterSpec struct {
+	APIVersion string          `json:"apiVersion"`
+	Kind       string          `json:"kind"`
+	Metadata   ClusterMetadata `json:"metadata"`
+	Spec       k3s.ClusterArgs `json:"spec"`
+}
+
+type ClusterMetadata struct {
+	Name string            `json:"name"`
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// ParseSpecs reads all cluster specs from r. The input can be YAML or JSON.
+// Multiple specs can be defined either as multiple YAML documents or as a list.
+// All specs are validated before returning, including that every cluster is only defined once,
+// and all problems are reported at once,
+// prefixed with the source and the number of the spec they occurred in.
+func ParseSpecs(r io.Reader, source string) ([]ClusterSpec, error) {
+	var documents []interface{}
+
+	decoder := yaml.NewDecoder(r)
+	for {
+		var document interface{}
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidConfig, source, err)
+		}
+
+		if list, ok := document.([]interface{}); ok {
+			documents = append(documents, list...)
+			continue
+		}
+
+		if document != nil {
+			documents = append(documents, document)
+		}
+	}
+
+	var (
+		specs = make([]ClusterSpec, 0, len(documents))
+		errs  []error
+		// names maps the name of every cluster to the number of the spec that defines it
+		names = map[string]int{}
+	)
+	for i, document := range documents {
+		spec, specErrs := parseSpec(document)
+
+		if name := spec.Metadata.Name; name != "" {
+			if other, ok := names[name]; ok {
+				specErrs = append(specErrs, &FieldError{
+					Field:   "metadata.name",
+					Problem: fmt.Sprintf("cluster %q is already defined in spec %d", name, other),
+				})
+			} else {
+				names[name] = i + 1
+			}
+		}
+
+		for _, err := range specErrs {
+			errs = append(errs, fmt.Errorf("%s, spec %d: %w", source, i+1, err))
+		}
+
+		specs = append(specs, spec)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return specs, nil
+}
+
+func parseSpec(document interface{}) (ClusterSpec, []error) {
+	spec := ClusterSpec{Spec: DefaultClusterArgs()}
+
+	if errs := validateSchema("", document, reflect.TypeOf(spec)); len(errs) > 0 {
+		return spec, errs
+	}
+
+	raw, err := json.Marshal(document)
+	if err != nil {
+		return spec, []error{err}
+	}
+
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return spec, []error{err}
+	}
+
+	var errs []error
+	if spec.APIVersion != SpecAPIVersion {
+		errs = append(errs, &FieldError{Field: "apiVersion", Problem: fmt.Sprintf("must be %q", SpecAPIVersion)})
+	}
+
+	if spec.Kind != SpecKind {
+		errs = append(errs, &FieldError{Field: "kind", Problem: fmt.Sprintf("must be %q", SpecKind)})
+	}
+
+	nameOptions := GlobalOptions{Name: spec.Metadata.Name}
+	errs = append(errs, prefixFields("metadata", errors.Join(nameOptions.ValidateName()))...)
+	errs = append(errs, prefixFields("metadata", nameOptions.validateNewName())...)
+	errs = append(errs, prefixFields("spec", spec.Spec.Validate())...)
+
+	return spec, errs
+}
+
+// prefixFields splits the joined errors of a validation and prefixes the fields of all FieldErrors with the given path.
+func prefixFields(prefix string, err error) []error {
+	if err == nil {
+		return nil
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+
+	var errs []error
+	for _, err := range joined.Unwrap() {
+		var fieldErr *FieldError
+		if errors.As(err, &fieldErr) {
+			errs = append(errs, &FieldError{Field: fieldPath(prefix, fieldErr.Field), Problem: fieldErr.Problem})
+			continue
+		}
+
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// validateSchema checks that value has the structure of the given type
+// as it would be decoded from JSON. This allows to report all unknown fields
+// and type mismatches with their full path instead of failing on the first one.
+func validateSchema(path string, value interface{}, t reflect.Type) []error {
+	mismatch := func(expected string) []error {
+		return []error{&FieldError{Field: fieldPath(path), Problem: fmt.Sprintf("expected %s, got %T", expected, value)}}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return mismatch("object")
+		}
+
+		fields := map[string]reflect.Type{}
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			fields[name] = t.Field(i).Type
+		}
+
+		var errs []error
+		for _, key := range sortedKeys(object) {
+			fieldType, ok := fields[key]
+			if !ok {
+				errs = append(errs, &FieldError{Field: fieldPath(path, key), Problem: "unknown field"})
+				continue
+			}
+
+			errs = append(errs, validateSchema(fieldPath(path, key), object[key], fieldType)...)
+		}
+
+		return errs
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return mismatch("object")
+		}
+
+		var errs []error
+		for _, key := range sortedKeys(object) {
+			errs = append(errs, validateSchema(fieldPath(path, key), object[key], t.Elem())...)
+		}
+
+		return errs
+	case reflect.Slice:
+		list, ok := value.([]interface{})
+		if !ok {
+			return mismatch("list")
+		}
+
+		var errs []error
+		for i, item := range list {
+			errs = append(errs, validateSchema(fmt.Sprintf("%s[%d]", path, i), item, t.Elem())...)
+		}
+
+		return errs
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			return mismatch("string")
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			return mismatch("boolean")
+		}
+	case reflect.Int:
+		switch number := value.(type) {
+		case int, int64, uint64:
+		case float64:
+			if number != math.Trunc(number) {
+				return mismatch("integer")
+			}
+		default:
+			return mismatch("integer")
+		}
+	default:
+	}
+
+	return nil
+}
+
+func fieldPath(path string, keys ...string) string {
+	for _, key := range keys {
+		if path != "" {
+			path += "."
+		}
+		path += key
+	}
+
+	if path == "" {
+		return "<root>"
+	}
+
+	return path
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
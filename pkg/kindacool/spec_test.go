@@ -0,0 +1,132 @@
+package kindacool
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPrefixFields(t *testing.T) {
+	errOther := errors.New("other")
+
+	tests := []struct {
+		name   string
+		prefix string
+		err    error
+		want   []string
+	}{
+		{name: "no error", prefix: "spec"},
+		{
+			name:   "single error",
+			prefix: "spec",
+			err:    errOther,
+			want:   []string{"other"},
+		},
+		{
+			name:   "joined field errors",
+			prefix: "spec",
+			err: errors.Join(
+				&FieldError{Field: "flavor", Problem: "is required"},
+				&FieldError{Field: "nodeCount", Problem: "must be at least 1, got 0"},
+			),
+			want: []string{"spec.flavor: is required", "spec.nodeCount: must be at least 1, got 0"},
+		},
+		{
+			name:   "other errors are kept",
+			prefix: "metadata",
+			err:    errors.Join(&FieldError{Field: "name", Problem: "is required"}, errOther),
+			want:   []string{"metadata.name: is required", "other"},
+		},
+		{
+			name: "empty prefix",
+			err:  errors.Join(&FieldError{Field: "name", Problem: "is required"}),
+			want: []string{"name: is required"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := prefixFields(tt.prefix, tt.err)
+
+			var got []string
+			for _, err := range errs {
+				got = append(got, err.Error())
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("prefixFields() = %q, want %q", got, tt.want)
+			}
+
+			for _, err := range errs {
+				var fieldErr *FieldError
+				if errors.As(err, &fieldErr) && !errors.Is(err, ErrInvalidConfig) {
+					t.Errorf("%v doesn't match ErrInvalidConfig", err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSpecs(t *testing.T) {
+	spec := func(name, spec string) string {
+		return "apiVersion: " + SpecAPIVersion + "\nkind: Cluster\nmetadata:\n  name: " + name + "\nspec:\n" + spec
+	}
+	privateSpec := "  privateNetworkName: internal\n"
+
+	tests := []struct {
+		name      string
+		input     string
+		wantNames []string
+		wantErrs  []string
+	}{
+		{
+			name:      "multiple documents",
+			input:     spec("a", privateSpec) + "---\n" + spec("b", privateSpec),
+			wantNames: []string{"a", "b"},
+		},
+		{
+			name:     "duplicate names",
+			input:    spec("a", privateSpec) + "---\n" + spec("b", privateSpec) + "---\n" + spec("a", privateSpec),
+			wantErrs: []string{`test.yaml, spec 3: metadata.name: cluster "a" is already defined in spec 1`},
+		},
+		{
+			name:  "invalid name and spec",
+			input: spec("My-Cluster", "  nodeCount: 0\n"),
+			wantErrs: []string{
+				`test.yaml, spec 1: metadata.name: "My-Cluster" must consist of lower case letters, digits and '-' ` +
+					`and start and end with a letter or digit`,
+				"test.yaml, spec 1: spec.nodeCount: must be at least 1, got 0",
+				"test.yaml, spec 1: spec.privateNetworkName: is required if public is not set",
+			},
+		},
+		{
+			name:     "unknown field",
+			input:    spec("a", privateSpec+"  nodes: 3\n"),
+			wantErrs: []string{"test.yaml, spec 1: spec.nodes: unknown field"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			specs, err := ParseSpecs(strings.NewReader(tt.input), "test.yaml")
+
+			if got := problems(err); !reflect.DeepEqual(got, tt.wantErrs) {
+				t.Errorf("ParseSpecs() errors = %q, want %q", got, tt.wantErrs)
+			}
+
+			if err != nil && !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("ParseSpecs() = %v, want it to match ErrInvalidConfig", err)
+			}
+
+			var names []string
+			for _, spec := range specs {
+				names = append(names, spec.Metadata.Name)
+			}
+
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("ParseSpecs() names = %q, want %q", names, tt.wantNames)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package kindacool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brumhard/kindacool/pkg/k3s"
+	"github.com/mitchellh/go-homedir"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	sshPort           = 22
+	defaultSSHTimeout = 10 * time.Second
+)
+
+var (
+	ErrNodeNotFound   = errors.New("node not found")
+	ErrNoNodes        = errors.New("cluster has no nodes, it might have been created by an older version")
+	ErrNoNodeAddress  = errors.New("node has no address that can be reached")
+	ErrInvalidBastion = errors.New("invalid bastion, expected [user@]host[:port]")
+)
+
+// SSHOptions configures how the nodes are reached.
+type SSHOptions struct {
+	// Bastion is an optional jump host in the format [user@]host[:port].
+	// If it is set, the nodes are reached by their private IP through the bastion.
+	Bastion string
+	// BastionKeyFile is a private key for the bastion.
+	// If it's not set, the ssh-agent and the cluster's key are used.
+	BastionKeyFile string
+	// Timeout for establishing connections. Defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+// NodeAccess contains everything needed to connect to the nodes of a cluster.
+type NodeAccess struct {
+	User    string
+	Nodes   []k3s.Node
+	signer  ssh.Signer
+	options SSHOptions
+}
+
+// NodeAccess collects the nodes and the ssh key of the current cluster from its stack outputs.
+func (m *Manager) NodeAccess(ctx context.Context, opts SSHOptions) (*NodeAccess, error) {
+	description, err := m.Describe(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(description.Nodes) == 0 {
+		return nil, ErrNoNodes
+	}
+
+	key, err := m.FetchOutput(ctx, OutputSSHKey)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey([]byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ssh key: %w", err)
+	}
+
+	machineUser := DefaultClusterArgs().MachineUser
+	if description.Args != nil && description.Args.MachineUser != "" {
+		machineUser = description.Args.MachineUser
+	}
+
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultSSHTimeout
+	}
+
+	return &NodeAccess{
+		User:    machineUser,
+		Nodes:   description.Nodes,
+		signer:  signer,
+		options: opts,
+	}, nil
+}
+
+// Node returns the node with the given name or index.
+// If the reference is empty, the server node is returned.
+func (a *NodeAccess) Node(ref string) (k3s.Node, error) {
+	if ref == "" {
+		for _, node := range a.Nodes {
+			if node.Role == k3s.RoleServer {
+				return node, nil
+			}
+		}
+
+		return a.Nodes[0], nil
+	}
+
+	if index, err := strconv.Atoi(ref); err == nil {
+		if index < 0 || index >= len(a.Nodes) {
+			return k3s.Node{}, fmt.Errorf("%w: index %d, the cluster has %d nodes", ErrNodeNotFound, index, len(a.Nodes))
+		}
+
+		return a.Nodes[index], nil
+	}
+
+	for _, node := range a.Nodes {
+		if node.Name == ref {
+			return node, nil
+		}
+	}
+
+	return k3s.Node{}, fmt.Errorf("%w: %q", ErrNodeNotFound, ref)
+}
+
+// SelectNodes returns all nodes that match the selector, e.g. role=worker.
+// Nodes can be selected by their name and role, an empty selector selects all nodes.
+func (a *NodeAccess) SelectNodes(selector Selector) ([]k3s.Node, error) {
+	var nodes []k3s.Node
+	for _, node := range a.Nodes {
+		if selector.Matches(map[string]string{"name": node.Name, "role": node.Role}) {
+			nodes = append(nodes, node)
+		}
+	}
+
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("%w: no node matches %q", ErrNodeNotFound, selector)
+	}
+
+	return nodes, nil
+}
+
+// Dial opens an SSH connection to the given node, through the bastion if one is configured.
+func (a *NodeAccess) Dial(ctx context.Context, node k3s.Node) (*ssh.Client, error) {
+	config := &ssh.ClientConfig{
+		User: a.User,
+		Auth: []ssh.AuthMethod{ssh.PublicKeys(a.signer)},
+		// the nodes are created with new host keys that are not known beforehand
+		//nolint:gosec // host keys of the nodes can't be verified
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         a.options.Timeout,
+	}
+
+	if a.options.Bastion == "" {
+		address := node.FloatingIP
+		if address == "" {
+			address = node.PrivateIP
+		}
+
+		if address == "" {
+			return nil, fmt.Errorf("%w: %s", ErrNoNodeAddress, node.Name)
+		}
+
+		return dialSSH(ctx, net.JoinHostPort(address, strconv.Itoa(sshPort)), config)
+	}
+
+	if node.PrivateIP == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNoNodeAddress, node.Name)
+	}
+
+	bastion, err := a.dialBastion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	address := net.JoinHostPort(node.PrivateIP, strconv.Itoa(sshPort))
+	conn, err := bastion.Dial("tcp", address)
+	if err != nil {
+		bastion.Close()
+		return nil, fmt.Errorf("failed to reach %s through bastion: %w", node.Name, err)
+	}
+
+	client, err := newClient(ctx, conn, address, config)
+	if err != nil {
+		bastion.Close()
+		return nil, fmt.Errorf("failed to connect to %s: %w", node.Name, err)
+	}
+
+	go func() {
+		_ = client.Wait()
+		bastion.Close()
+	}()
+
+	return client, nil
+}
+
+func (a *NodeAccess) dialBastion(ctx context.Context) (*ssh.Client, error) {
+	bastionUser, address, err := parseBastion(a.options.Bastion)
+	if err != nil {
+		return nil, err
+	}
+
+	var auth []ssh.AuthMethod
+	if a.options.BastionKeyFile != "" {
+		keyFile, err := homedir.Expand(a.options.BastionKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse bastion key: %w", err)
+		}
+
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			// the agent is only used to authenticate while connecting
+			defer conn.Close()
+			auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+
+	auth = append(auth, ssh.PublicKeys(a.signer))
+
+	hostKeyCallback, err := bastionHostKeyCallback()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := dialSSH(ctx, address, &ssh.ClientConfig{
+		User:            bastionUser,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         a.options.Timeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to bastion: %w", err)
+	}
+
+	return client, nil
+}
+
+func dialSSH(ctx context.Context, address string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	dialer := net.Dialer{Timeout: config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newClient(ctx, conn, address, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// newClient runs the SSH handshake on the connection.
+// The handshake is limited by the config's timeout and stopped when the context is canceled,
+// so that an unresponsive server can't block it forever.
+func newClient(ctx context.Context, conn net.Conn, address string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	if config.Timeout > 0 {
+		// connections through the bastion don't support deadlines, they only rely on the context
+		_ = conn.SetDeadline(time.Now().Add(config.Timeout))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	clientConn, channels, requests, err := ssh.NewClientConn(conn, address, config)
+	close(done)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if err == nil {
+			clientConn.Close()
+		}
+
+		return nil, ctxErr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+
+	return ssh.NewClient(clientConn, channels, requests), nil
+}
+
+// bastionHostKeyCallback verifies the bastion with the user's known_hosts file if it exists.
+func bastionHostKeyCallback() (ssh.HostKeyCallback, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	knownHostsFile := path.Join(home, ".ssh", "known_hosts")
+	if _, err := os.Stat(knownHostsFile); err != nil {
+		//nolint:gosec // there is nothing to verify against
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	return knownhosts.New(knownHostsFile)
+}
+
+// parseBastion splits [user@]host[:port] into the user and the address.
+// The current user and port 22 are used as defaults.
+func parseBastion(bastion string) (string, string, error) {
+	bastionUser, hostPort, found := strings.Cut(bastion, "@")
+	if !found {
+		hostPort = bastionUser
+		bastionUser = ""
+		if current, err := user.Current(); err == nil {
+			bastionUser = current.Username
+		}
+	}
+
+	if hostPort == "" || bastionUser == "" {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidBastion, bastion)
+	}
+
+	if _, _, err := net.SplitHostPort(hostPort); err != nil {
+		host := strings.TrimSuffix(strings.TrimPrefix(hostPort, "["), "]")
+		hostPort = net.JoinHostPort(host, strconv.Itoa(sshPort))
+	}
+
+	return bastionUser, hostPort, nil
+}
@@ -0,0 +1,72 @@
+package kindacool
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestDialSSHUnresponsiveServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// accepts connections but never starts the handshake
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		cancel  bool
+		wantErr error
+	}{
+		{name: "timeout", timeout: 100 * time.Millisecond},
+		{name: "canceled", cancel: true, wantErr: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				time.AfterFunc(100*time.Millisecond, cancel)
+			}
+
+			config := &ssh.ClientConfig{
+				User: "test",
+				//nolint:gosec // the handshake never gets to the host key
+				HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+				Timeout:         tt.timeout,
+			}
+
+			result := make(chan error, 1)
+			go func() {
+				_, err := dialSSH(ctx, listener.Addr().String(), config)
+				result <- err
+			}()
+
+			select {
+			case err := <-result:
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Errorf("dialSSH() = %v, want %v", err, tt.wantErr)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("dialSSH() didn't return for an unresponsive server")
+			}
+		})
+	}
+}
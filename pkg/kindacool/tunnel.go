@@ -0,0 +1,211 @@
+package kindacool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/brumhard/kindacool/pkg/k3s"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	tunnelKeepAliveInterval = 15 * time.Second
+	tunnelReconnectInterval = 5 * time.Second
+)
+
+// Tunnel forwards all connections of the listener to the port on the node until the context is done.
+// If the SSH connection drops, it is reestablished and new connections wait until it is up again.
+// Only the first connection attempt fails the tunnel, afterwards errors are logged and retried.
+func (a *NodeAccess) Tunnel(ctx context.Context, node k3s.Node, listener net.Listener, remotePort int, logger *log.Logger) error {
+	client, err := a.Dial(ctx, node)
+	if err != nil {
+		return err
+	}
+
+	t := &tunnel{ready: make(chan struct{})}
+	remoteAddress := net.JoinHostPort("127.0.0.1", strconv.Itoa(remotePort))
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+	go t.accept(ctx, listener, remoteAddress, logger)
+
+	for {
+		t.connected(client)
+		logger.Printf("Tunnel from %s to %s:%d is up", listener.Addr(), node.Name, remotePort)
+
+		keepAlive(ctx, client)
+
+		t.disconnected()
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		logger.Println("Connection lost, reconnecting")
+		client = a.redial(ctx, node, logger)
+		if client == nil {
+			return nil
+		}
+	}
+}
+
+// redial connects to the node until it succeeds or the context is done.
+func (a *NodeAccess) redial(ctx context.Context, node k3s.Node, logger *log.Logger) *ssh.Client {
+	for {
+		client, err := a.Dial(ctx, node)
+		if err == nil {
+			return client
+		}
+
+		logger.Printf("Failed to reconnect, retrying in %s: %v", tunnelReconnectInterval, err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(tunnelReconnectInterval):
+		}
+	}
+}
+
+// keepAlive blocks until the connection is lost or the context is done.
+// The connection is closed in both cases.
+func keepAlive(ctx context.Context, client *ssh.Client) {
+	closed := make(chan struct{})
+	go func() {
+		_ = client.Wait()
+		close(closed)
+	}()
+	defer client.Close()
+
+	ticker := time.NewTicker(tunnelKeepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-closed:
+			return
+		case <-ticker.C:
+			replied := make(chan error, 1)
+			go func() {
+				_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+				replied <- err
+			}()
+
+			// a dead connection doesn't necessarily return an error, so the reply is also timed out
+			select {
+			case err := <-replied:
+				if err != nil {
+					return
+				}
+			case <-time.After(tunnelKeepAliveInterval):
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+type tunnel struct {
+	mu     sync.Mutex
+	client *ssh.Client
+	// ready is closed as soon as a client is connected.
+	ready chan struct{}
+}
+
+func (t *tunnel) connected(client *ssh.Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.client = client
+	close(t.ready)
+}
+
+func (t *tunnel) disconnected() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.client = nil
+	t.ready = make(chan struct{})
+}
+
+// current waits until a client is connected.
+func (t *tunnel) current(ctx context.Context) (*ssh.Client, error) {
+	for {
+		t.mu.Lock()
+		client, ready := t.client, t.ready
+		t.mu.Unlock()
+
+		if client != nil {
+			return client, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ready:
+		}
+	}
+}
+
+func (t *tunnel) accept(ctx context.Context, listener net.Listener, remoteAddress string, logger *log.Logger) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				logger.Printf("Failed to accept connection: %v", err)
+			}
+
+			return
+		}
+
+		go func() {
+			if err := t.forward(ctx, conn, remoteAddress); err != nil && ctx.Err() == nil {
+				logger.Printf("Failed to forward connection: %v", err)
+			}
+		}()
+	}
+}
+
+func (t *tunnel) forward(ctx context.Context, conn net.Conn, remoteAddress string) error {
+	defer conn.Close()
+
+	client, err := t.current(ctx)
+	if err != nil {
+		return err
+	}
+
+	remote, err := client.Dial("tcp", remoteAddress)
+	if err != nil {
+		return err
+	}
+	defer remote.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(remote, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(conn, remote)
+		done <- struct{}{}
+	}()
+
+	// as soon as one side is finished, both connections are closed by the deferred calls
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	return nil
+}